@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE queries ADD COLUMN assertions jsonb NOT NULL DEFAULT '[]'::jsonb;
+
+-- +goose Down
+ALTER TABLE queries DROP COLUMN assertions;
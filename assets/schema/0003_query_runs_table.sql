@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE query_runs(
+    run_id UUID PRIMARY KEY,
+    query_id UUID,
+    name VARCHAR(40),
+    data_product_id UUID,
+    parameters jsonb,
+    sql_hash CHAR(64),
+    value DOUBLE PRECISION,
+    status VARCHAR(10),
+    duration_ms BIGINT,
+    error TEXT,
+    workflow_id TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX query_runs_query_id_started_at_idx ON query_runs (query_id, started_at DESC);
+CREATE INDEX query_runs_name_started_at_idx ON query_runs (data_product_id, name, started_at DESC);
+
+-- +goose Down
+DROP TABLE query_runs;
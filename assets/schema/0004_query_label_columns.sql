@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE queries ADD COLUMN label_columns TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE queries ADD COLUMN value_columns TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE query_runs ADD COLUMN series jsonb;
+
+-- +goose Down
+ALTER TABLE query_runs DROP COLUMN series;
+ALTER TABLE queries DROP COLUMN value_columns;
+ALTER TABLE queries DROP COLUMN label_columns;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE queries ADD COLUMN anomaly jsonb;
+
+-- +goose Down
+ALTER TABLE queries DROP COLUMN anomaly;
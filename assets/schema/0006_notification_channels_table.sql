@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE notification_channels(
+    channel_id UUID PRIMARY KEY,
+    data_product_id UUID,
+    query_id UUID,
+    type VARCHAR(20) NOT NULL,
+    config jsonb NOT NULL,
+    min_status VARCHAR(10) NOT NULL DEFAULT 'FAIL',
+    CHECK (data_product_id IS NOT NULL OR query_id IS NOT NULL)
+);
+
+CREATE INDEX notification_channels_data_product_id_idx ON notification_channels (data_product_id);
+CREATE INDEX notification_channels_query_id_idx ON notification_channels (query_id);
+
+-- +goose Down
+DROP TABLE notification_channels;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE data_sources(
+    data_product_id UUID PRIMARY KEY,
+    type VARCHAR(20) NOT NULL,
+    config jsonb NOT NULL
+);
+
+-- +goose Down
+DROP TABLE data_sources;
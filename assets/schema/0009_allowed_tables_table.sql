@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE allowed_tables(
+    data_product_id UUID PRIMARY KEY,
+    tables TEXT[] NOT NULL
+);
+
+-- +goose Down
+DROP TABLE allowed_tables;
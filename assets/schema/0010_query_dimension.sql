@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE queries ADD COLUMN dimension TEXT;
+ALTER TABLE queries ADD COLUMN weight DOUBLE PRECISION NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE queries DROP COLUMN weight;
+ALTER TABLE queries DROP COLUMN dimension;
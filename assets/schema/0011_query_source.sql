@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE queries ADD COLUMN source TEXT;
+
+-- +goose Down
+ALTER TABLE queries DROP COLUMN source;
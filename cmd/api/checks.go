@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"xcaliber/data-quality-metrics-framework/internal/checks"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// syncChecks makes the query catalog and the schedules match the checks
+// declared in the checks directory. Checks are matched to stored queries
+// by data product and name, so existing queries are taken over by the file
+// that declares them. Queries declared in a file that no longer declares
+// them are deleted together with their schedule. Nothing is changed if
+// any check is invalid.
+func (app *application) syncChecks(ctx context.Context) error {
+	_, err := os.Stat(app.config.checksDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		app.logger.Info("no checks directory, skipping sync", slog.String("dir", app.config.checksDir))
+		return nil
+	}
+
+	definitions, err := checks.Load(app.config.checksDir)
+	if err != nil {
+		return err
+	}
+
+	queries := make([]*database.Query, len(definitions))
+	var problems []string
+	for i, def := range definitions {
+		query, err := app.checkQuery(ctx, def)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s: %v", def.Source, def.Name, err))
+			continue
+		}
+		queries[i] = query
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid checks, nothing was synced: %s", strings.Join(problems, "; "))
+	}
+
+	existing, err := app.db.ListQueries(ctx, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]database.Query, len(existing))
+	for _, query := range existing {
+		stored[checks.Definition{DataProductID: query.DataProductID, Name: query.Name}.Key()] = query
+	}
+
+	var (
+		created, updated, deleted int
+		declared                  = make(map[uuid.UUID]bool, len(queries))
+	)
+	for i, query := range queries {
+		def := definitions[i]
+
+		if current, ok := stored[def.Key()]; ok {
+			query.QueryID = current.QueryID
+			_, err = app.db.UpdateQuery(ctx, query)
+			updated++
+		} else {
+			err = app.db.InsertQuery(ctx, query)
+			created++
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", def.Source, def.Name, err)
+		}
+		declared[query.QueryID] = true
+
+		if def.Schedule != nil {
+			_, err = app.scheduler.CreateSchedule(ctx, query.QueryID, def.Schedule.Cron, def.Schedule.Parameters, def.Schedule.Paused)
+		} else {
+			_, err = app.scheduler.DeleteSchedule(ctx, query.QueryID)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s: schedule: %w", def.Source, def.Name, err)
+		}
+	}
+
+	for _, query := range existing {
+		if query.Source == "" || declared[query.QueryID] {
+			continue
+		}
+		_, err = app.scheduler.DeleteSchedule(ctx, query.QueryID)
+		if err != nil {
+			return fmt.Errorf("%s: %s: schedule: %w", query.Source, query.Name, err)
+		}
+		_, err = app.db.DeleteQuery(ctx, query.QueryID)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", query.Source, query.Name, err)
+		}
+		deleted++
+	}
+
+	app.logger.Info("checks synced",
+		slog.String("dir", app.config.checksDir),
+		slog.Int("created", created),
+		slog.Int("updated", updated),
+		slog.Int("deleted", deleted),
+	)
+	return nil
+}
+
+// checkQuery validates a declared check like POST /queries and
+// POST /queries/{id}/schedule validate their requests and returns the
+// query to store.
+func (app *application) checkQuery(ctx context.Context, def checks.Definition) (*database.Query, error) {
+	input := AddQueryInput{payload: AddQueryRequest{
+		Name:              def.Name,
+		DataProductID:     def.DataProductID,
+		Query:             def.Query,
+		Description:       def.Description,
+		DefaultParameters: def.Parameters,
+		Assertions:        def.Assertions,
+		LabelColumns:      def.LabelColumns,
+		ValueColumns:      def.ValueColumns,
+		Anomaly:           def.Anomaly,
+		Template:          def.Template,
+		Dimension:         def.Dimension,
+		Weight:            def.Weight,
+	}}
+	if !app.validateAddQueryRequestParameters(&input) {
+		return nil, validationError(input.Validator)
+	}
+
+	if def.Schedule != nil {
+		schedule := ScheduleQueryInput{payload: ScheduleQueryRequest{
+			Cron:       def.Schedule.Cron,
+			Parameters: def.Schedule.Parameters,
+			Paused:     def.Schedule.Paused,
+		}}
+		if !app.validateScheduleQueryRequestParameters(&schedule) {
+			return nil, validationError(schedule.Validator)
+		}
+	}
+
+	query, err := input.toQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.validateReadOnly(ctx, &input.Validator, query.DataProductID, query.Query)
+	if err != nil {
+		return nil, err
+	}
+	if input.Validator.HasErrors() {
+		return nil, validationError(input.Validator)
+	}
+
+	query.Source = def.Source
+	return query, nil
+}
+
+// syncChecksOnHangup syncs the checks again whenever the process receives
+// SIGHUP, so changed files are picked up without a restart.
+func (app *application) syncChecksOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		app.logger.Info("received SIGHUP, syncing checks")
+		err := app.syncChecks(context.Background())
+		if err != nil {
+			app.logger.Error("could not sync checks", slog.Any("err", err))
+		}
+	}
+}
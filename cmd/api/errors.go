@@ -1,13 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
 	"strings"
 
+	"xcaliber/data-quality-metrics-framework/internal/auth"
+	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
 	"xcaliber/data-quality-metrics-framework/internal/response"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
 	"xcaliber/data-quality-metrics-framework/internal/validator"
 )
 
@@ -56,10 +60,43 @@ func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request)
 	app.errorMessage(w, r, http.StatusMethodNotAllowed, message, nil)
 }
 
+func (app *application) unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	headers := make(http.Header)
+	headers.Set("WWW-Authenticate", "Bearer")
+
+	message := "Missing or invalid API key or token"
+	if errors.Is(err, auth.ErrNoCredentials) {
+		message = "Authentication is required to access this resource"
+	}
+	app.errorMessage(w, r, http.StatusUnauthorized, message, headers)
+}
+
+func (app *application) forbidden(w http.ResponseWriter, r *http.Request) {
+	message := "You are not allowed to access this resource"
+	app.errorMessage(w, r, http.StatusForbidden, message, nil)
+}
+
 func (app *application) badRequest(w http.ResponseWriter, r *http.Request, err error) {
 	app.errorMessage(w, r, http.StatusBadRequest, err.Error(), nil)
 }
 
+// runError reports a failed query run. Queries rejected by the SQL guard
+// are reported as failed validation and transient data gateway failures
+// as 503 so clients know to try again later.
+func (app *application) runError(w http.ResponseWriter, r *http.Request, err error) {
+	if utility.IsUnsafeQuery(err) {
+		var v validator.Validator
+		v.AddFieldError("Query", err.Error())
+		app.failedValidation(w, r, v)
+		return
+	}
+	if datagateway.IsRetryable(err) {
+		app.errorMessage(w, r, http.StatusServiceUnavailable, err.Error(), nil)
+		return
+	}
+	app.badRequest(w, r, err)
+}
+
 func (app *application) failedValidation(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -1,13 +1,32 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/auth"
 	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+	"xcaliber/data-quality-metrics-framework/internal/metrics"
+	"xcaliber/data-quality-metrics-framework/internal/notify"
 	"xcaliber/data-quality-metrics-framework/internal/request"
 	"xcaliber/data-quality-metrics-framework/internal/response"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
+	"xcaliber/data-quality-metrics-framework/internal/scorecard"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
 	"xcaliber/data-quality-metrics-framework/internal/utility"
 	"xcaliber/data-quality-metrics-framework/internal/validator"
+	"xcaliber/data-quality-metrics-framework/internal/workflow"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
@@ -39,13 +58,15 @@ func (app *application) validateRunQueryRequestParameters(
 		"Name",
 		"Name is required",
 	)
+	// the run history stores names like the catalog, in VARCHAR(40)
 	input.Validator.CheckField(
-		input.payload.Query != "",
-		"Query",
-		"Query is required",
+		len(input.payload.Name) <= 40,
+		"Name",
+		"Name must not be more than 40 characters long",
 	)
+	validateTemplate(&input.Validator, input.payload.Query, input.payload.Template)
 	input.Validator.CheckField(
-		input.payload.Parameters != nil,
+		input.payload.Parameters != nil || input.payload.Template != nil,
 		"Parameters",
 		"Parameters is required",
 	)
@@ -54,9 +75,76 @@ func (app *application) validateRunQueryRequestParameters(
 		"DataProductID",
 		"DataProductID is required",
 	)
+	if err := input.payload.Assertions.Validate(); err != nil {
+		input.Validator.AddFieldError("Assertions", err.Error())
+	}
+	validateColumns(&input.Validator, input.payload.LabelColumns, input.payload.ValueColumns)
+
+	return !input.Validator.HasErrors()
+
+}
+
+// validateReadOnly adds a Query error to v unless query is a single
+// read-only statement that only reads tables the data product may read.
+func (app *application) validateReadOnly(ctx context.Context, v *validator.Validator, dataProductID uuid.UUID, query string) error {
+	allowedTables, err := app.db.ListAllowedTables(ctx, dataProductID)
+	if err != nil {
+		return err
+	}
+
+	err = utility.CheckReadOnly(query, allowedTables)
+	if err != nil {
+		v.AddFieldError("Query", err.Error())
+	}
+	return nil
+}
+
+// validateTemplate checks that a check is given either as SQL or as a
+// template.
+func validateTemplate(v *validator.Validator, query string, template *templates.Spec) {
+	if template == nil {
+		v.CheckField(query != "", "Query", "Query is required")
+		return
+	}
+	v.CheckField(query == "", "Query", "Query must not be set together with Template")
+	if err := template.Validate(); err != nil {
+		v.AddFieldError("Template", err.Error())
+	}
+}
+
+// applyTemplate generates the SQL for template and adds the parameters it
+// references to parameters.
+func applyTemplate(template *templates.Spec, parameters json.RawMessage) (string, json.RawMessage, error) {
+	query, templateParameters, err := template.Render()
+	if err != nil {
+		return "", nil, err
+	}
 
-	return input.Validator.HasErrors()
+	parameters, err = utility.MergeParameters(parameters, templateParameters)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, parameters, nil
+}
 
+// validateColumns checks that label columns can be used as Prometheus labels
+// and are only given together with value columns.
+func validateColumns(v *validator.Validator, labelColumns []string, valueColumns []string) {
+	v.CheckField(
+		len(labelColumns) == 0 || len(valueColumns) > 0,
+		"ValueColumns",
+		"ValueColumns is required when LabelColumns is set",
+	)
+	for _, column := range labelColumns {
+		v.CheckField(
+			metrics.ValidLabelName(column),
+			"LabelColumns",
+			fmt.Sprintf("%q is not a valid label name, label columns must match [a-zA-Z_][a-zA-Z0-9_]* and must not be one of %v", column, metrics.ReservedLabels),
+		)
+	}
+	for _, column := range valueColumns {
+		v.CheckField(column != "", "ValueColumns", "ValueColumns must not contain empty names")
+	}
 }
 
 type RunQueryInput struct {
@@ -69,11 +157,24 @@ type RunQueryInput struct {
 // @Description Endpoint to Run a stored query
 // @Tags run
 // @Produce  json
-// @Success 201 {object} map[string]string "{"Data":map[string]interface{},"Status": "OK", "Message":"Query executed successfully"}"
+// @Param dry_run query bool false "Only render the SQL and report the substituted, unresolved and unused parameters"
+// @Success 201 {object} map[string]string "{"Data":{"rows":[],"value":0,"status":"PASS","assertions":[]},"Status": "OK", "Message":"Query executed successfully"}"
+// @Success 200 {object} map[string]string "{"Data":{"sql":"","values":{},"substituted":[],"unresolved":[],"unused":[]},"Status": "OK", "Message":"Query rendered successfully"}"
 // @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 422 {object} validator.Validator
 // @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
 // @Router /run [post]
 func (app *application) RunQuey(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			app.badRequest(w, r, errors.New("dry_run must be true or false"))
+			return
+		}
+	}
+
 	var input RunQueryInput
 	err := request.DecodeJSON(w, r, &input.payload)
 	if err != nil {
@@ -81,30 +182,1841 @@ func (app *application) RunQuey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok := app.validateRunQueryRequestParameters(&input)
+	check, ok := app.checkRunQueryRequest(w, r, &input)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), defaultRunTimeout)
+	defer cancel()
+
+	if dryRun {
+		rendering, err := app.runner.Render(ctx, *check)
+		if err != nil {
+			app.runError(w, r, err)
+			return
+		}
+
+		res := StandardResponse{
+			Status:  http.StatusText(http.StatusOK),
+			Message: "Query rendered successfully, it was not executed",
+			Data:    rendering,
+		}
+		err = response.JSON(w, http.StatusOK, res)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	results, err := app.runner.Run(ctx, *check)
+	if err != nil {
+		app.runError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Query executed successfully",
+		Data:    results,
+	}
+	err = response.JSON(w, http.StatusCreated, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// checkRunQueryRequest validates an ad hoc check, makes sure the caller may
+// run checks of its data product and generates the SQL of templates. It
+// sends the error response and reports false if the check cannot run.
+func (app *application) checkRunQueryRequest(w http.ResponseWriter, r *http.Request, input *RunQueryInput) (*database.Query, bool) {
+	ok := app.validateRunQueryRequestParameters(input)
+	if !ok {
+		app.failedValidation(w, r, input.Validator)
+		return nil, false
+	}
+
+	if !app.authorizeDataProduct(w, r, input.payload.DataProductID, auth.RoleRunner) {
+		return nil, false
+	}
+
+	query, parameters := input.payload.Query, input.payload.Parameters
+	if input.payload.Template != nil {
+		var err error
+		query, parameters, err = applyTemplate(input.payload.Template, parameters)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return nil, false
+		}
+	}
+
+	err := app.validateReadOnly(r.Context(), &input.Validator, input.payload.DataProductID, query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, false
+	}
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return nil, false
+	}
+
+	return &database.Query{
+		DataProductID: input.payload.DataProductID,
+		Name:          input.payload.Name,
+		Query:         query,
+		Parameters:    parameters,
+		Assertions:    input.payload.Assertions,
+		LabelColumns:  input.payload.LabelColumns,
+		ValueColumns:  input.payload.ValueColumns,
+	}, true
+}
+
+func (app *application) validateAddQueryRequestParameters(
+	input *AddQueryInput,
+) bool {
+
+	input.Validator.CheckField(
+		input.payload.Name != "",
+		"Name",
+		"Name is required",
+	)
+	input.Validator.CheckField(
+		len(input.payload.Name) <= 40,
+		"Name",
+		"Name must not be more than 40 characters long",
+	)
+	validateTemplate(&input.Validator, input.payload.Query, input.payload.Template)
+	input.Validator.CheckField(
+		input.payload.DataProductID != uuid.Nil,
+		"DataProductID",
+		"DataProductID is required",
+	)
+
+	var defaults map[string]interface{}
+	input.Validator.CheckField(
+		input.payload.DefaultParameters == nil || json.Unmarshal(input.payload.DefaultParameters, &defaults) == nil,
+		"DefaultParameters",
+		"DefaultParameters must be a JSON object",
+	)
+	if err := input.payload.Assertions.Validate(); err != nil {
+		input.Validator.AddFieldError("Assertions", err.Error())
+	}
+	if input.payload.Anomaly != nil {
+		if err := input.payload.Anomaly.Validate(); err != nil {
+			input.Validator.AddFieldError("Anomaly", err.Error())
+		}
+		input.Validator.CheckField(
+			len(input.payload.ValueColumns) == 0,
+			"Anomaly",
+			"Anomaly models are only supported for single-value queries",
+		)
+	}
+	validateColumns(&input.Validator, input.payload.LabelColumns, input.payload.ValueColumns)
+	input.Validator.CheckField(
+		scorecard.ValidDimension(input.payload.Dimension),
+		"Dimension",
+		"Dimension must be one of "+strings.Join(scorecard.Dimensions, ", "),
+	)
+	input.Validator.CheckField(
+		input.payload.Weight == nil || *input.payload.Weight >= 0,
+		"Weight",
+		"Weight must not be negative",
+	)
+
+	return !input.Validator.HasErrors()
+
+}
+
+// List check templates
+// @Summary List check templates
+// @Description Endpoint to list the check templates that can be used instead of SQL in /run and /queries
+// @Tags templates
+// @Produce  json
+// @Success 200 {object} StandardResponse
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /templates [get]
+func (app *application) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Templates fetched successfully",
+		Data:    templates.Types,
+	}
+	err := response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+type AddQueryInput struct {
+	payload   AddQueryRequest
+	Validator validator.Validator `json:"-"`
+}
+
+// toQuery builds the stored query. Queries defined by a template store the
+// generated SQL, with the template's parameters added to the defaults.
+func (input *AddQueryInput) toQuery() (*database.Query, error) {
+	query, defaults := input.payload.Query, input.payload.DefaultParameters
+	if input.payload.Template != nil {
+		var err error
+		query, defaults, err = applyTemplate(input.payload.Template, defaults)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if defaults == nil {
+		defaults = json.RawMessage("{}")
+	}
+
+	dimension := input.payload.Dimension
+	if dimension == "" && input.payload.Template != nil {
+		dimension = scorecard.TemplateDimension(input.payload.Template.Type)
+	}
+	weight := 1.0
+	if input.payload.Weight != nil {
+		weight = *input.payload.Weight
+	}
+
+	return &database.Query{
+		DataProductID:     input.payload.DataProductID,
+		Name:              input.payload.Name,
+		Description:       input.payload.Description,
+		Query:             query,
+		DefaultParameters: defaults,
+		Assertions:        input.payload.Assertions,
+		LabelColumns:      input.payload.LabelColumns,
+		ValueColumns:      input.payload.ValueColumns,
+		Anomaly:           input.payload.Anomaly,
+		Template:          input.payload.Template,
+		Dimension:         dimension,
+		Weight:            weight,
+	}, nil
+}
+
+// queryIDParam parses the {id} URL parameter, reporting false when it is not
+// a valid UUID.
+func queryIDParam(r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Add query
+// @Summary Add query
+// @Description Endpoint to store a parameterized query in the catalog
+// @Tags queries
+// @Accept  json
+// @Produce  json
+// @Param query body AddQueryRequest true "Query definition"
+// @Success 201 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries [post]
+func (app *application) AddQuery(w http.ResponseWriter, r *http.Request) {
+	var input AddQueryInput
+	err := request.DecodeJSON(w, r, &input.payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ok := app.validateAddQueryRequestParameters(&input)
 	if !ok {
 		app.failedValidation(w, r, input.Validator)
 		return
 	}
 
-	queryStr, err := utility.FormatQuery(input.payload.Query, input.payload.Parameters)
+	if !app.authorizeDataProduct(w, r, input.payload.DataProductID, auth.RoleAdmin) {
+		return
+	}
+
+	query, err := input.toQuery()
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.validateReadOnly(r.Context(), &input.Validator, query.DataProductID, query.Query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	err = app.db.InsertQuery(r.Context(), query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusCreated),
+		Message: "Query created successfully",
+		Data:    query,
+	}
+	err = response.JSON(w, http.StatusCreated, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// List queries
+// @Summary List queries
+// @Description Endpoint to list stored queries, optionally filtered by data product
+// @Tags queries
+// @Produce  json
+// @Param data_product_id query string false "Data product ID"
+// @Success 200 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries [get]
+func (app *application) ListQueries(w http.ResponseWriter, r *http.Request) {
+	dataProductID := uuid.Nil
+	if v := r.URL.Query().Get("data_product_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			app.badRequest(w, r, errors.New("data_product_id must be a valid UUID"))
+			return
+		}
+		dataProductID = id
+	}
+
+	queries, err := app.db.ListQueries(r.Context(), dataProductID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	principal := app.principal(r)
+	visible := queries[:0]
+	for _, query := range queries {
+		if principal.CanAccess(query.DataProductID, auth.RoleViewer) {
+			visible = append(visible, query)
+		}
+	}
+	queries = visible
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Queries fetched successfully",
+		Data:    queries,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Get query
+// @Summary Get query
+// @Description Endpoint to fetch a stored query by ID
+// @Tags queries
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id} [get]
+func (app *application) GetQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	query, found, err := app.db.GetQuery(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Query fetched successfully",
+		Data:    query,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Update query
+// @Summary Update query
+// @Description Endpoint to replace a stored query definition
+// @Tags queries
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Param query body AddQueryRequest true "Query definition"
+// @Success 200 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id} [put]
+func (app *application) UpdateQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var input AddQueryInput
+	err := request.DecodeJSON(w, r, &input.payload)
 	if err != nil {
 		app.badRequest(w, r, err)
 		return
 	}
 
-	results, err := datagateway.RunQuery(app.config.dataGatewayURL, queryStr)
+	ok = app.validateAddQueryRequestParameters(&input)
+	if !ok {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	// the caller must also own the data product the query is moved to
+	if !app.authorizeDataProduct(w, r, input.payload.DataProductID, auth.RoleAdmin) {
+		return
+	}
+
+	query, err := input.toQuery()
 	if err != nil {
 		app.badRequest(w, r, err)
 		return
 	}
+	query.QueryID = id
+
+	err = app.validateReadOnly(r.Context(), &input.Validator, query.DataProductID, query.Query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	found, err := app.db.UpdateQuery(r.Context(), query)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
 
 	res := StandardResponse{
 		Status:  http.StatusText(http.StatusOK),
-		Message: "Query executed successfully",
-		Data:    results,
+		Message: "Query updated successfully",
+		Data:    query,
 	}
-	err = response.JSON(w, http.StatusCreated, res)
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete query
+// @Summary Delete query
+// @Description Endpoint to delete a stored query
+// @Tags queries
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id} [delete]
+func (app *application) DeleteQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	// the schedule goes first, so a failure leaves the query in place to
+	// retry the delete instead of a schedule running a missing query
+	_, err := app.scheduler.DeleteSchedule(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	found, err := app.db.DeleteQuery(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Query deleted successfully",
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Run stored query by ID
+// @Summary Run stored query by ID
+// @Description Endpoint to run a query from the catalog, merging the supplied parameters over its defaults
+// @Tags queries
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Param parameters body RunStoredQueryRequest false "Parameter overrides"
+// @Success 201 {object} map[string]string "{"Data":{"rows":[],"value":0,"status":"PASS","assertions":[]},"Status": "OK", "Message":"Query executed successfully"}"
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/run [post]
+func (app *application) RunStoredQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var payload RunStoredQueryRequest
+	if r.ContentLength != 0 {
+		err := request.DecodeJSON(w, r, &payload)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	query, found, err := app.db.GetQuery(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	query.Parameters, err = utility.MergeParameters(query.DefaultParameters, payload.Parameters)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), defaultRunTimeout)
+	defer cancel()
+
+	results, err := app.runner.Run(ctx, *query)
+	if err != nil {
+		app.runError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Query executed successfully",
+		Data:    results,
+	}
+	err = response.JSON(w, http.StatusCreated, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+type ScheduleQueryInput struct {
+	payload   ScheduleQueryRequest
+	Validator validator.Validator `json:"-"`
+}
+
+func (app *application) validateScheduleQueryRequestParameters(
+	input *ScheduleQueryInput,
+) bool {
+
+	input.Validator.CheckField(
+		strings.TrimSpace(input.payload.Cron) != "",
+		"Cron",
+		"Cron is required",
+	)
+
+	var parameters map[string]interface{}
+	input.Validator.CheckField(
+		input.payload.Parameters == nil || json.Unmarshal(input.payload.Parameters, &parameters) == nil,
+		"Parameters",
+		"Parameters must be a JSON object",
+	)
+
+	return !input.Validator.HasErrors()
+
+}
+
+// Schedule query
+// @Summary Schedule query
+// @Description Endpoint to run a stored query periodically on a cron schedule, replacing any existing schedule
+// @Tags schedules
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Param schedule body ScheduleQueryRequest true "Schedule definition"
+// @Success 201 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/schedule [post]
+func (app *application) ScheduleQuery(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var input ScheduleQueryInput
+	err := request.DecodeJSON(w, r, &input.payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ok = app.validateScheduleQueryRequestParameters(&input)
+	if !ok {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	_, found, err := app.db.GetQuery(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	schedule, err := app.scheduler.CreateSchedule(r.Context(), id, input.payload.Cron, input.payload.Parameters, input.payload.Paused)
+	if err != nil {
+		if workflow.IsInvalidSchedule(err) {
+			app.badRequest(w, r, err)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusCreated),
+		Message: "Query scheduled successfully",
+		Data:    schedule,
+	}
+	err = response.JSON(w, http.StatusCreated, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Get query schedule
+// @Summary Get query schedule
+// @Description Endpoint to fetch the schedule of a stored query, including its next run times
+// @Tags schedules
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/schedule [get]
+func (app *application) GetQuerySchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	schedule, found, err := app.scheduler.GetSchedule(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Schedule fetched successfully",
+		Data:    schedule,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete query schedule
+// @Summary Delete query schedule
+// @Description Endpoint to stop running a stored query periodically
+// @Tags schedules
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/schedule [delete]
+func (app *application) DeleteQuerySchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	found, err := app.scheduler.DeleteSchedule(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Schedule deleted successfully",
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Pause query schedule
+// @Summary Pause query schedule
+// @Description Endpoint to pause the schedule of a stored query
+// @Tags schedules
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Param note body ScheduleStateRequest false "Reason for pausing"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/schedule/pause [post]
+func (app *application) PauseQuerySchedule(w http.ResponseWriter, r *http.Request) {
+	app.setQueryScheduleState(w, r, true)
+}
+
+// Resume query schedule
+// @Summary Resume query schedule
+// @Description Endpoint to resume a paused schedule of a stored query
+// @Tags schedules
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Param note body ScheduleStateRequest false "Reason for resuming"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/schedule/resume [post]
+func (app *application) ResumeQuerySchedule(w http.ResponseWriter, r *http.Request) {
+	app.setQueryScheduleState(w, r, false)
+}
+
+func (app *application) setQueryScheduleState(w http.ResponseWriter, r *http.Request, pause bool) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var payload ScheduleStateRequest
+	if r.ContentLength != 0 {
+		err := request.DecodeJSON(w, r, &payload)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	var (
+		found   bool
+		err     error
+		message string
+	)
+	if pause {
+		found, err = app.scheduler.PauseSchedule(r.Context(), id, payload.Note)
+		message = "Schedule paused successfully"
+	} else {
+		found, err = app.scheduler.ResumeSchedule(r.Context(), id, payload.Note)
+		message = "Schedule resumed successfully"
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: message,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// List query runs
+// @Summary List query runs
+// @Description Endpoint to list the run history of a stored query, newest first
+// @Tags queries
+// @Produce  json
+// @Param id path string true "Query ID"
+// @Param from query string false "Only runs started at or after this RFC 3339 time"
+// @Param to query string false "Only runs started before this RFC 3339 time"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Runs per page, at most 100"
+// @Success 200 {object} StandardResponse{data=QueryRunsResponse}
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /queries/{id}/runs [get]
+func (app *application) ListQueryRuns(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var v validator.Validator
+	qs := r.URL.Query()
+
+	filter := database.QueryRunFilter{
+		QueryID:  id,
+		From:     readTime(qs, "from", &v),
+		To:       readTime(qs, "to", &v),
+		Page:     readInt(qs, "page", 1, &v),
+		PageSize: readInt(qs, "page_size", 20, &v),
+	}
+
+	v.CheckField(filter.Page >= 1, "page", "page must be greater than zero")
+	v.CheckField(filter.PageSize >= 1 && filter.PageSize <= 100, "page_size", "page_size must be between 1 and 100")
+	v.CheckField(filter.From.IsZero() || filter.To.IsZero() || filter.From.Before(filter.To), "from", "from must be before to")
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	_, found, err := app.db.GetQuery(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	runs, total, err := app.db.ListQueryRuns(r.Context(), filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Query runs fetched successfully",
+		Data: QueryRunsResponse{
+			Runs: runs,
+			Metadata: PageMetadata{
+				Page:         filter.Page,
+				PageSize:     filter.PageSize,
+				TotalRecords: total,
+			},
+		},
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddFieldError(key, key+" must be an RFC 3339 timestamp")
+		return time.Time{}
+	}
+	return t
+}
+
+func readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddFieldError(key, key+" must be an integer value")
+		return defaultValue
+	}
+	return i
+}
+
+type NotificationChannelInput struct {
+	payload   NotificationChannelRequest
+	Validator validator.Validator `json:"-"`
+}
+
+func (app *application) validateNotificationChannelRequestParameters(
+	input *NotificationChannelInput,
+) bool {
+
+	input.Validator.CheckField(
+		input.payload.DataProductID != uuid.Nil || input.payload.QueryID != uuid.Nil,
+		"DataProductID",
+		"DataProductID or QueryID is required",
+	)
+	input.Validator.CheckField(
+		input.payload.MinStatus == "" ||
+			input.payload.MinStatus == string(assertion.StatusWarn) ||
+			input.payload.MinStatus == string(assertion.StatusFail),
+		"MinStatus",
+		"MinStatus must be WARN or FAIL",
+	)
+	_, err := notify.New(input.payload.Type, input.payload.Config, app.config.smtp, nil)
+	if err != nil {
+		input.Validator.AddFieldError("Config", err.Error())
+	}
+
+	return !input.Validator.HasErrors()
+
+}
+
+// Add notification channel
+// @Summary Add notification channel
+// @Description Endpoint to send alerts for a query or for every query of a data product to a webhook, Slack, email or PagerDuty
+// @Tags notifications
+// @Accept  json
+// @Produce  json
+// @Param channel body NotificationChannelRequest true "Channel definition"
+// @Success 201 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /notification-channels [post]
+func (app *application) AddNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	var input NotificationChannelInput
+	err := request.DecodeJSON(w, r, &input.payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ok := app.validateNotificationChannelRequestParameters(&input)
+	if !ok {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	channel := &database.NotificationChannel{
+		DataProductID: uuid.NullUUID{UUID: input.payload.DataProductID, Valid: input.payload.DataProductID != uuid.Nil},
+		QueryID:       uuid.NullUUID{UUID: input.payload.QueryID, Valid: input.payload.QueryID != uuid.Nil},
+		Type:          input.payload.Type,
+		Config:        input.payload.Config,
+		MinStatus:     input.payload.MinStatus,
+	}
+	if channel.MinStatus == "" {
+		channel.MinStatus = string(assertion.StatusFail)
+	}
+
+	dataProductID, found, err := app.channelDataProduct(r.Context(), channel)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.badRequest(w, r, errors.New("query_id does not reference a stored query"))
+		return
+	}
+	if !app.authorizeDataProduct(w, r, dataProductID, auth.RoleAdmin) {
+		return
+	}
+
+	err = app.db.InsertNotificationChannel(r.Context(), channel)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	channel.Config = notify.Redact(channel.Type, channel.Config)
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusCreated),
+		Message: "Notification channel created successfully",
+		Data:    channel,
+	}
+	err = response.JSON(w, http.StatusCreated, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// List notification channels
+// @Summary List notification channels
+// @Description Endpoint to list notification channels, optionally filtered by data product or query, with the secrets in their config redacted
+// @Tags notifications
+// @Produce  json
+// @Param data_product_id query string false "Data product ID"
+// @Param query_id query string false "Query ID"
+// @Success 200 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /notification-channels [get]
+func (app *application) ListNotificationChannels(w http.ResponseWriter, r *http.Request) {
+	var ids [2]uuid.UUID
+	for i, key := range []string{"data_product_id", "query_id"} {
+		if v := r.URL.Query().Get(key); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				app.badRequest(w, r, fmt.Errorf("%s must be a valid UUID", key))
+				return
+			}
+			ids[i] = id
+		}
+	}
+
+	channels, err := app.db.ListNotificationChannels(r.Context(), ids[0], ids[1])
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	principal := app.principal(r)
+	if !principal.AllDataProducts {
+		visible := channels[:0]
+		for i := range channels {
+			dataProductID, found, err := app.channelDataProduct(r.Context(), &channels[i])
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			if found && principal.CanAccess(dataProductID, auth.RoleViewer) {
+				visible = append(visible, channels[i])
+			}
+		}
+		channels = visible
+	}
+
+	// viewers may list channels, the secrets to post to them stay hidden
+	for i := range channels {
+		channels[i].Config = notify.Redact(channels[i].Type, channels[i].Config)
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Notification channels fetched successfully",
+		Data:    channels,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete notification channel
+// @Summary Delete notification channel
+// @Description Endpoint to delete a notification channel
+// @Tags notifications
+// @Produce  json
+// @Param id path string true "Channel ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /notification-channels/{id} [delete]
+func (app *application) DeleteNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	channel, found, err := app.db.GetNotificationChannel(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	// channels of deleted queries can only be removed by callers with
+	// access to every data product
+	dataProductID, _, err := app.channelDataProduct(r.Context(), channel)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !app.authorizeDataProduct(w, r, dataProductID, auth.RoleAdmin) {
+		return
+	}
+
+	found, err = app.db.DeleteNotificationChannel(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Notification channel deleted successfully",
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// channelDataProduct returns the data product of a notification channel,
+// which for channels of a single query is the data product of the query.
+// It reports false if the query does not exist.
+func (app *application) channelDataProduct(ctx context.Context, channel *database.NotificationChannel) (uuid.UUID, bool, error) {
+	if channel.DataProductID.Valid {
+		return channel.DataProductID.UUID, true, nil
+	}
+
+	query, found, err := app.db.GetQuery(ctx, channel.QueryID.UUID)
+	if err != nil || !found {
+		return uuid.Nil, false, err
+	}
+	return query.DataProductID, true, nil
+}
+
+// Set data source
+// @Summary Set data source
+// @Description Endpoint to run the queries of a data product against a gateway, Postgres, SQLite or DuckDB instead of the default data gateway
+// @Tags data sources
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Param source body DataSourceRequest true "Data source definition"
+// @Success 200 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/data-source [put]
+func (app *application) PutDataSource(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var payload DataSourceRequest
+	err := request.DecodeJSON(w, r, &payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var v validator.Validator
+	err = datagateway.Validate(payload.Type, payload.Config)
+	if err != nil {
+		v.AddFieldError("Config", err.Error())
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	source := &database.DataSource{
+		DataProductID: id,
+		Type:          payload.Type,
+		Config:        payload.Config,
+	}
+	err = app.db.PutDataSource(r.Context(), source)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	source.Config = datagateway.Redact(source.Config)
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Data source saved successfully",
+		Data:    source,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Get data source
+// @Summary Get data source
+// @Description Endpoint to fetch the data source of a data product, with passwords and other secrets in its config redacted
+// @Tags data sources
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/data-source [get]
+func (app *application) GetDataSource(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	source, found, err := app.db.GetDataSource(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	// viewers may read the data source, its credentials stay hidden
+	source.Config = datagateway.Redact(source.Config)
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Data source fetched successfully",
+		Data:    source,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete data source
+// @Summary Delete data source
+// @Description Endpoint to remove the data source of a data product, its queries run against the default data gateway again
+// @Tags data sources
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/data-source [delete]
+func (app *application) DeleteDataSource(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	found, err := app.db.DeleteDataSource(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Data source deleted successfully",
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Set allowed tables
+// @Summary Set allowed tables
+// @Description Endpoint to limit the tables the queries of a data product may read, as table, schema.table or schema.*
+// @Tags data sources
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Param tables body AllowedTablesRequest true "Allowed tables"
+// @Success 200 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/allowed-tables [put]
+func (app *application) PutAllowedTables(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var payload AllowedTablesRequest
+	err := request.DecodeJSON(w, r, &payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var v validator.Validator
+	v.CheckField(len(payload.Tables) > 0, "Tables", "Tables is required, delete the allowlist to allow every table")
+	for _, table := range payload.Tables {
+		v.CheckField(utility.ValidTableName(table), "Tables", fmt.Sprintf("%q is not a table, schema.table or schema.* name", table))
+	}
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	allowed := &database.AllowedTables{
+		DataProductID: id,
+		Tables:        payload.Tables,
+	}
+	err = app.db.PutAllowedTables(r.Context(), allowed)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Allowed tables saved successfully",
+		Data:    allowed,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Get allowed tables
+// @Summary Get allowed tables
+// @Description Endpoint to fetch the tables the queries of a data product may read
+// @Tags data sources
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/allowed-tables [get]
+func (app *application) GetAllowedTables(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	allowed, found, err := app.db.GetAllowedTables(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Allowed tables fetched successfully",
+		Data:    allowed,
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete allowed tables
+// @Summary Delete allowed tables
+// @Description Endpoint to remove the table allowlist of a data product, its queries may read any table again
+// @Tags data sources
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/allowed-tables [delete]
+func (app *application) DeleteAllowedTables(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	found, err := app.db.DeleteAllowedTables(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !found {
+		app.notFound(w, r)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Allowed tables deleted successfully",
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Start run
+// @Summary Start run
+// @Description Endpoint to run a stored query (query_id and optional parameters) or an ad hoc check (like /run) in the background through the Temporal workflow
+// @Tags runs
+// @Accept  json
+// @Produce  json
+// @Param run body StartRunRequest true "Query to run"
+// @Success 202 {object} StandardResponse
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /runs [post]
+func (app *application) StartRun(w http.ResponseWriter, r *http.Request) {
+	var payload StartRunRequest
+	err := request.DecodeJSON(w, r, &payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var check *database.Query
+	if payload.QueryID != uuid.Nil {
+		stored, found, err := app.db.GetQuery(r.Context(), payload.QueryID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if !found {
+			app.notFound(w, r)
+			return
+		}
+		if !app.authorizeDataProduct(w, r, stored.DataProductID, auth.RoleRunner) {
+			return
+		}
+		if len(payload.Parameters) > 0 {
+			// fail now rather than in the workflow
+			_, err = utility.MergeParameters(stored.DefaultParameters, payload.Parameters)
+			if err != nil {
+				app.badRequest(w, r, err)
+				return
+			}
+		}
+
+		// the workflow runs the stored SQL with these parameters over the
+		// defaults of the query
+		check = &database.Query{
+			QueryID:       stored.QueryID,
+			DataProductID: stored.DataProductID,
+			Name:          stored.Name,
+			Parameters:    payload.Parameters,
+		}
+	} else {
+		var ok bool
+		check, ok = app.checkRunQueryRequest(w, r, &RunQueryInput{payload: payload.RunQueryRequest})
+		if !ok {
+			return
+		}
+	}
+
+	run, err := app.runs.Start(r.Context(), *check)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", "/runs/"+run.RunID.String())
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusAccepted),
+		Message: "Run started successfully",
+		Data:    run,
+	}
+	err = response.JSONWithHeaders(w, http.StatusAccepted, res, headers)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// getRun fetches the run in the {id} URL parameter and makes sure the
+// caller may act on its data product with role. It sends the error
+// response and reports false otherwise.
+func (app *application) getRun(w http.ResponseWriter, r *http.Request, role auth.Role) (*workflow.Run, bool) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	run, found, err := app.runs.Get(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, false
+	}
+	if !found {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	if !app.authorizeDataProduct(w, r, run.DataProductID, role) {
+		return nil, false
+	}
+	return run, true
+}
+
+// Get run
+// @Summary Get run
+// @Description Endpoint to poll the status of a run started with POST /runs, and its result once it has completed
+// @Tags runs
+// @Produce  json
+// @Param id path string true "Run ID"
+// @Success 200 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /runs/{id} [get]
+func (app *application) GetRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := app.getRun(w, r, auth.RoleViewer)
+	if !ok {
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Run fetched successfully",
+		Data:    run,
+	}
+	err := response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Cancel run
+// @Summary Cancel run
+// @Description Endpoint to cancel a run started with POST /runs, which also cancels its query
+// @Tags runs
+// @Produce  json
+// @Param id path string true "Run ID"
+// @Success 202 {object} StandardResponse
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 409 {object} map[string]string "{"error": "run has already finished"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /runs/{id} [delete]
+func (app *application) CancelRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := app.getRun(w, r, auth.RoleRunner)
+	if !ok {
+		return
+	}
+
+	if run.Status != workflow.RunStatusRunning {
+		app.errorMessage(w, r, http.StatusConflict, fmt.Sprintf("run has already finished with status %s", run.Status), nil)
+		return
+	}
+
+	err := app.runs.Cancel(r.Context(), run.RunID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusAccepted),
+		Message: "Run cancellation requested",
+	}
+	err = response.JSON(w, http.StatusAccepted, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// maxBatchSize bounds the queries of one batch run.
+const maxBatchSize = 100
+
+// BatchRunItem is the outcome of one query of a batch run.
+type BatchRunItem struct {
+	Name    string     `json:"name"`
+	QueryID *uuid.UUID `json:"query_id,omitempty"`
+	runner.BatchResult
+}
+
+// Run batch
+// @Summary Run batch
+// @Description Endpoint to run many stored queries (query_id and optional parameters) or ad hoc checks (like /run) in one call. Items run in parallel and fail on their own; with fail_fast the first item that errors or fails its assertions skips the items not yet started
+// @Tags run
+// @Accept  json
+// @Produce  json
+// @Param batch body BatchRunRequest true "Queries to run"
+// @Success 200 {object} map[string]string "{"Data":{"results":[{"index":0,"name":"","result":{}}],"summary":{"total":1,"passed":1,"warned":0,"failed":0,"errored":0,"skipped":0}},"Status": "OK", "Message":"Batch executed"}"
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 422 {object} validator.Validator
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /run/batch [post]
+func (app *application) RunBatch(w http.ResponseWriter, r *http.Request) {
+	var payload BatchRunRequest
+	err := request.DecodeJSON(w, r, &payload)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var v validator.Validator
+	v.CheckField(len(payload.Queries) > 0, "Queries", "Queries is required")
+	v.CheckField(len(payload.Queries) <= maxBatchSize, "Queries", fmt.Sprintf("Queries must not contain more than %d items", maxBatchSize))
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	ctx, cancel, err := app.batchContext(w, r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer cancel()
+
+	items := make([]BatchRunItem, len(payload.Queries))
+	for i, item := range payload.Queries {
+		items[i].Name = item.Name
+		if item.QueryID != uuid.Nil {
+			queryID := item.QueryID
+			items[i].QueryID = &queryID
+		}
+	}
+
+	batch := runner.Batch{
+		Workers:  app.config.batchRunWorkers,
+		FailFast: payload.FailFast,
+	}
+	results := batch.Run(ctx, len(payload.Queries), func(ctx context.Context, i int) (*runner.Result, error) {
+		query, err := app.batchQuery(ctx, r, payload.Queries[i])
+		if err != nil {
+			return nil, err
+		}
+		items[i].Name = query.Name
+		return app.runner.Run(ctx, *query)
+	})
+	for i := range results {
+		items[i].BatchResult = results[i]
+	}
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Batch executed",
+		Data: map[string]interface{}{
+			"results": items,
+			"summary": runner.Summarize(results),
+		},
+	}
+	err = response.JSON(w, http.StatusOK, res)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// batchContext bounds a batch by the batch run timeout and extends the
+// write deadline of the response accordingly, as batches outlast the write
+// timeout of other requests.
+func (app *application) batchContext(w http.ResponseWriter, r *http.Request) (context.Context, context.CancelFunc, error) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(app.config.batchRunTimeout + time.Second))
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), app.config.batchRunTimeout)
+	return ctx, cancel, nil
+}
+
+// batchQuery prepares one item of a batch run like POST /runs does,
+// returning what would be an error response as the error of the item.
+func (app *application) batchQuery(ctx context.Context, r *http.Request, item StartRunRequest) (*database.Query, error) {
+	principal := app.principal(r)
+
+	if item.QueryID != uuid.Nil {
+		query, found, err := app.db.GetQuery(ctx, item.QueryID)
+		if err != nil {
+			return nil, err
+		}
+		if !found || !principal.CanAccess(query.DataProductID, auth.RoleViewer) {
+			return nil, fmt.Errorf("query %s not found", item.QueryID)
+		}
+		if !principal.CanAccess(query.DataProductID, auth.RoleRunner) {
+			return nil, fmt.Errorf("not allowed to run query %s", item.QueryID)
+		}
+		query.Parameters, err = utility.MergeParameters(query.DefaultParameters, item.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		return query, nil
+	}
+
+	input := RunQueryInput{payload: item.RunQueryRequest}
+	if !app.validateRunQueryRequestParameters(&input) {
+		return nil, validationError(input.Validator)
+	}
+	if !principal.CanAccess(input.payload.DataProductID, auth.RoleRunner) {
+		return nil, fmt.Errorf("not allowed to run queries of data product %s", input.payload.DataProductID)
+	}
+
+	query, parameters := input.payload.Query, input.payload.Parameters
+	if input.payload.Template != nil {
+		var err error
+		query, parameters, err = applyTemplate(input.payload.Template, parameters)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the runner applies the read-only guard
+	return &database.Query{
+		DataProductID: input.payload.DataProductID,
+		Name:          input.payload.Name,
+		Query:         query,
+		Parameters:    parameters,
+		Assertions:    input.payload.Assertions,
+		LabelColumns:  input.payload.LabelColumns,
+		ValueColumns:  input.payload.ValueColumns,
+	}, nil
+}
+
+// validationError joins the errors of v into one error, fields in order.
+func validationError(v validator.Validator) error {
+	messages := append([]string{}, v.Errors...)
+	fields := make([]string, 0, len(v.FieldErrors))
+	for field := range v.FieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, v.FieldErrors[field])
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// Run data product suite
+// @Summary Run data product suite
+// @Description Endpoint to run every stored query of a data product, merging the supplied parameters over their defaults, and rate its health overall and per dimension (completeness, validity, freshness, uniqueness). The scores are also published as data_product_quality_score
+// @Tags data products
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Data product ID"
+// @Param parameters body RunStoredQueryRequest false "Parameter overrides"
+// @Success 200 {object} map[string]string "{"Data":{"data_product_id":"","score":87.5,"dimensions":[{"dimension":"completeness","score":100,"weight":2,"checks":2}],"checks":[]},"Status": "OK", "Message":"Suite executed"}"
+// @Failure 400 {object} map[string]string "{"error": "invalid request"}"
+// @Failure 404 {object} map[string]string "{"error": "not found"}"
+// @Failure 500 {object} map[string]string "{"error": "Internal server error"}"
+// @Router /data-products/{id}/run [post]
+func (app *application) RunDataProductSuite(w http.ResponseWriter, r *http.Request) {
+	id, ok := queryIDParam(r)
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	var payload RunStoredQueryRequest
+	if r.ContentLength != 0 {
+		err := request.DecodeJSON(w, r, &payload)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	queries, err := app.db.ListQueries(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(queries) == 0 {
+		app.notFound(w, r)
+		return
+	}
+
+	ctx, cancel, err := app.batchContext(w, r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer cancel()
+
+	batch := runner.Batch{Workers: app.config.batchRunWorkers}
+	results := batch.Run(ctx, len(queries), func(ctx context.Context, i int) (*runner.Result, error) {
+		query := queries[i]
+		var err error
+		query.Parameters, err = utility.MergeParameters(query.DefaultParameters, payload.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		return app.runner.Run(ctx, query)
+	})
+
+	checks := make([]scorecard.Check, len(queries))
+	for i, query := range queries {
+		checks[i] = scorecard.Check{
+			QueryID:   query.QueryID,
+			Name:      query.Name,
+			Dimension: query.Dimension,
+			Weight:    query.Weight,
+			Error:     results[i].Error,
+		}
+		if results[i].Skipped {
+			checks[i].Error = "not run before the suite timed out"
+		}
+		if result := results[i].Result; result != nil {
+			checks[i].RunID = result.RunID
+			checks[i].Status = result.Status
+		}
+	}
+	card := scorecard.Compute(id, checks)
+
+	scores := make(map[string]float64, len(card.Dimensions)+1)
+	if card.Score != nil {
+		scores[metrics.OverallDimension] = *card.Score
+	}
+	for _, d := range card.Dimensions {
+		if d.Score != nil {
+			scores[d.Dimension] = *d.Score
+		}
+	}
+	metrics.SetQualityScores(scores, id.String())
+
+	res := StandardResponse{
+		Status:  http.StatusText(http.StatusOK),
+		Message: "Suite executed",
+		Data:    card,
+	}
+	err = response.JSON(w, http.StatusOK, res)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
@@ -5,8 +5,10 @@ import (
 	"log/slog"
 	"net/http"
 
+	"xcaliber/data-quality-metrics-framework/internal/auth"
 	"xcaliber/data-quality-metrics-framework/internal/response"
 
+	"github.com/google/uuid"
 	"github.com/tomasen/realip"
 )
 
@@ -42,3 +44,114 @@ func (app *application) logAccess(next http.Handler) http.Handler {
 		app.logger.Info("access", userAttrs, requestAttrs, responseAttrs)
 	})
 }
+
+// authenticate rejects requests without valid credentials and stores the
+// caller in the request context. When authentication is disabled every
+// request acts as auth.Anonymous.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.Anonymous
+		if !app.config.authDisabled {
+			var err error
+			principal, err = app.authenticator.Authenticate(r)
+			if err != nil {
+				app.unauthorized(w, r, err)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+}
+
+// requireRole rejects callers whose role does not include role.
+func (app *application) requireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.principal(r).Role.Includes(role) {
+				app.forbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireDataProductAccess rejects callers that may not act with role on
+// the data product in the {id} URL parameter.
+func (app *application) requireDataProductAccess(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := queryIDParam(r)
+			if ok && !app.principal(r).CanAccess(id, role) {
+				app.forbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireQueryAccess rejects callers that may not act with role on the
+// data product of the stored query in the {id} URL parameter. Unknown
+// queries are left to the handler to report.
+func (app *application) requireQueryAccess(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := queryIDParam(r)
+			if ok {
+				query, found, err := app.db.GetQuery(r.Context(), id)
+				if err != nil {
+					app.serverError(w, r, err)
+					return
+				}
+				if found && !app.principal(r).CanAccess(query.DataProductID, role) {
+					app.forbidden(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireUnmanagedQuery rejects changes to stored queries declared in a
+// checks file, which are only changed through the file.
+func (app *application) requireUnmanagedQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := queryIDParam(r)
+		if ok {
+			query, found, err := app.db.GetQuery(r.Context(), id)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			if found && query.Source != "" {
+				message := fmt.Sprintf("query is declared in the checks file %s and can only be changed there", query.Source)
+				app.errorMessage(w, r, http.StatusConflict, message, nil)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// principal returns the caller of r. Requests that did not pass through
+// authenticate get a principal without any permissions.
+func (app *application) principal(r *http.Request) *auth.Principal {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return &auth.Principal{}
+	}
+	return principal
+}
+
+// authorizeDataProduct reports whether the caller may act with role on the
+// data product, sending a 403 response if not.
+func (app *application) authorizeDataProduct(w http.ResponseWriter, r *http.Request, dataProductID uuid.UUID, role auth.Role) bool {
+	if !app.principal(r).CanAccess(dataProductID, role) {
+		app.forbidden(w, r)
+		return false
+	}
+	return true
+}
@@ -2,21 +2,81 @@ package main
 
 import (
 	"encoding/json"
+	"xcaliber/data-quality-metrics-framework/internal/anomaly"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
 
 	"github.com/google/uuid"
 )
 
-// type AddQueryRequest struct {
-// 	Name              string          `json:"name"               binding:"required"`
-// 	DataProductID     uuid.UUID       `json:"data_product_id"    binding:"required"`
-// 	Query             string          `json:"query"              binding:"required"`
-// 	Description       string          `json:"description"        binding:"required"`
-// 	DefaultParameters json.RawMessage `json:"default_parameters" binding:"required"`
-// }
+type AddQueryRequest struct {
+	Name              string               `json:"name"               binding:"required"`
+	DataProductID     uuid.UUID            `json:"data_product_id"    binding:"required"`
+	Query             string               `json:"query"              binding:"required"`
+	Description       string               `json:"description"        binding:"required"`
+	DefaultParameters json.RawMessage      `json:"default_parameters" binding:"required"`
+	Assertions        assertion.Assertions `json:"assertions"`
+	LabelColumns      []string             `json:"label_columns"`
+	ValueColumns      []string             `json:"value_columns"`
+	Anomaly           *anomaly.Model       `json:"anomaly"`
+	Template          *templates.Spec      `json:"template"`
+	Dimension         string               `json:"dimension"`
+	Weight            *float64             `json:"weight"`
+}
 
 type RunQueryRequest struct {
-	Name          string          `json:"name"               binding:"required"`
-	DataProductID uuid.UUID       `json:"data_product_id"    binding:"required"`
-	Query         string          `json:"query"              binding:"required"`
-	Parameters    json.RawMessage `json:"parameters" binding:"required"`
+	Name          string               `json:"name"               binding:"required"`
+	DataProductID uuid.UUID            `json:"data_product_id"    binding:"required"`
+	Query         string               `json:"query"              binding:"required"`
+	Parameters    json.RawMessage      `json:"parameters" binding:"required"`
+	Assertions    assertion.Assertions `json:"assertions"`
+	LabelColumns  []string             `json:"label_columns"`
+	ValueColumns  []string             `json:"value_columns"`
+	Template      *templates.Spec      `json:"template"`
+}
+
+type ScheduleQueryRequest struct {
+	Cron       string          `json:"cron"       binding:"required"`
+	Parameters json.RawMessage `json:"parameters"`
+	Paused     bool            `json:"paused"`
+}
+
+type ScheduleStateRequest struct {
+	Note string `json:"note"`
+}
+
+type RunStoredQueryRequest struct {
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+type NotificationChannelRequest struct {
+	DataProductID uuid.UUID       `json:"data_product_id"`
+	QueryID       uuid.UUID       `json:"query_id"`
+	Type          string          `json:"type"       binding:"required"`
+	Config        json.RawMessage `json:"config"     binding:"required"`
+	MinStatus     string          `json:"min_status"`
+}
+
+type DataSourceRequest struct {
+	Type   string          `json:"type"   binding:"required"`
+	Config json.RawMessage `json:"config" binding:"required"`
+}
+
+type AllowedTablesRequest struct {
+	Tables []string `json:"tables" binding:"required"`
+}
+
+// StartRunRequest starts either a stored query, given by QueryID, or an ad
+// hoc check given like the body of /run.
+type StartRunRequest struct {
+	QueryID uuid.UUID `json:"query_id"`
+	RunQueryRequest
+}
+
+// BatchRunRequest runs many stored queries or ad hoc checks in one call.
+// With FailFast the first item that errors or fails its assertions stops
+// the batch.
+type BatchRunRequest struct {
+	Queries  []StartRunRequest `json:"queries"   binding:"required"`
+	FailFast bool              `json:"fail_fast"`
 }
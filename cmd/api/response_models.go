@@ -1,7 +1,20 @@
 package main
 
+import "xcaliber/data-quality-metrics-framework/internal/database"
+
 type StandardResponse struct {
 	Data    interface{} `json:"data"`
 	Status  string      `json:"status"`
 	Message string      `json:"message"`
 }
+
+type PageMetadata struct {
+	Page         int `json:"page"`
+	PageSize     int `json:"page_size"`
+	TotalRecords int `json:"total_records"`
+}
+
+type QueryRunsResponse struct {
+	Runs     []database.QueryRun `json:"runs"`
+	Metadata PageMetadata        `json:"metadata"`
+}
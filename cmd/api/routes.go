@@ -3,6 +3,8 @@ package main
 import (
 	"net/http"
 
+	"xcaliber/data-quality-metrics-framework/internal/auth"
+
 	chiprometheus "github.com/edjumacator/chi-prometheus"
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -27,7 +29,56 @@ func (app *application) routes() http.Handler {
 	// Health
 	mux.Get("/health", app.HealthHandler)
 
-	mux.Post("/run", app.RunQuey)
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.authenticate)
+
+		// The role of the caller is checked per route; handlers check the
+		// data products named in request bodies.
+		viewer := app.requireRole(auth.RoleViewer)
+		runner := app.requireRole(auth.RoleRunner)
+		admin := app.requireRole(auth.RoleAdmin)
+
+		mux.With(runner).Post("/run", app.RunQuey)
+		mux.With(runner).Post("/run/batch", app.RunBatch)
+		mux.With(viewer).Get("/templates", app.ListTemplates)
+
+		// Asynchronous runs
+		mux.With(runner).Post("/runs", app.StartRun)
+		mux.With(viewer).Get("/runs/{id}", app.GetRun)
+		mux.With(runner).Delete("/runs/{id}", app.CancelRun)
+
+		// Query catalog
+		mux.With(admin).Post("/queries", app.AddQuery)
+		mux.With(viewer).Get("/queries", app.ListQueries)
+		mux.With(app.requireQueryAccess(auth.RoleViewer)).Get("/queries/{id}", app.GetQuery)
+		mux.With(app.requireQueryAccess(auth.RoleAdmin), app.requireUnmanagedQuery).Put("/queries/{id}", app.UpdateQuery)
+		mux.With(app.requireQueryAccess(auth.RoleAdmin), app.requireUnmanagedQuery).Delete("/queries/{id}", app.DeleteQuery)
+		mux.With(app.requireQueryAccess(auth.RoleRunner)).Post("/queries/{id}/run", app.RunStoredQuery)
+		mux.With(app.requireQueryAccess(auth.RoleViewer)).Get("/queries/{id}/runs", app.ListQueryRuns)
+
+		// Schedules
+		mux.With(app.requireQueryAccess(auth.RoleAdmin), app.requireUnmanagedQuery).Post("/queries/{id}/schedule", app.ScheduleQuery)
+		mux.With(app.requireQueryAccess(auth.RoleViewer)).Get("/queries/{id}/schedule", app.GetQuerySchedule)
+		mux.With(app.requireQueryAccess(auth.RoleAdmin), app.requireUnmanagedQuery).Delete("/queries/{id}/schedule", app.DeleteQuerySchedule)
+		mux.With(app.requireQueryAccess(auth.RoleAdmin), app.requireUnmanagedQuery).Post("/queries/{id}/schedule/pause", app.PauseQuerySchedule)
+		mux.With(app.requireQueryAccess(auth.RoleAdmin), app.requireUnmanagedQuery).Post("/queries/{id}/schedule/resume", app.ResumeQuerySchedule)
+
+		// Data product suites
+		mux.With(app.requireDataProductAccess(auth.RoleRunner)).Post("/data-products/{id}/run", app.RunDataProductSuite)
+
+		// Data sources
+		mux.With(app.requireDataProductAccess(auth.RoleAdmin)).Put("/data-products/{id}/data-source", app.PutDataSource)
+		mux.With(app.requireDataProductAccess(auth.RoleViewer)).Get("/data-products/{id}/data-source", app.GetDataSource)
+		mux.With(app.requireDataProductAccess(auth.RoleAdmin)).Delete("/data-products/{id}/data-source", app.DeleteDataSource)
+		mux.With(app.requireDataProductAccess(auth.RoleAdmin)).Put("/data-products/{id}/allowed-tables", app.PutAllowedTables)
+		mux.With(app.requireDataProductAccess(auth.RoleViewer)).Get("/data-products/{id}/allowed-tables", app.GetAllowedTables)
+		mux.With(app.requireDataProductAccess(auth.RoleAdmin)).Delete("/data-products/{id}/allowed-tables", app.DeleteAllowedTables)
+
+		// Notifications
+		mux.With(admin).Post("/notification-channels", app.AddNotificationChannel)
+		mux.With(viewer).Get("/notification-channels", app.ListNotificationChannels)
+		mux.With(admin).Delete("/notification-channels/{id}", app.DeleteNotificationChannel)
+	})
 
 	return mux
 }
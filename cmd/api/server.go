@@ -17,6 +17,10 @@ const (
 	defaultReadTimeout    = 5 * time.Second
 	defaultWriteTimeout   = 10 * time.Second
 	defaultShutdownPeriod = 30 * time.Second
+
+	// defaultRunTimeout bounds queries run from a request, leaving time to
+	// write the response before defaultWriteTimeout.
+	defaultRunTimeout = defaultWriteTimeout - time.Second
 )
 
 func (app *application) serveHTTP() error {
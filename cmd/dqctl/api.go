@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiFlags are the flags of the commands that call the API.
+type apiFlags struct {
+	url     string
+	apiKey  string
+	timeout time.Duration
+}
+
+func (f *apiFlags) register(fs *flag.FlagSet) {
+	url := os.Getenv("DQCTL_API_URL")
+	if url == "" {
+		url = "http://localhost:4444"
+	}
+	fs.StringVar(&f.url, "api", url, "API URL, defaults to $DQCTL_API_URL")
+	fs.StringVar(&f.apiKey, "api-key", os.Getenv("DQCTL_API_KEY"), "API key, defaults to $DQCTL_API_KEY")
+	fs.DurationVar(&f.timeout, "timeout", 5*time.Minute, "upper bound for the API call")
+}
+
+func (f *apiFlags) client() *apiClient {
+	return &apiClient{
+		url:    strings.TrimSuffix(f.url, "/"),
+		apiKey: f.apiKey,
+		http:   &http.Client{Timeout: f.timeout},
+	}
+}
+
+// apiClient calls the API of the server.
+type apiClient struct {
+	url    string
+	apiKey string
+	http   *http.Client
+}
+
+// apiError is the body of error responses: either a message or the errors
+// of a failed validation.
+type apiError struct {
+	Error       string            `json:"Error"`
+	Errors      []string          `json:"Errors"`
+	FieldErrors map[string]string `json:"FieldErrors"`
+}
+
+func (e apiError) message() string {
+	messages := append([]string{}, e.Errors...)
+	fields := make([]string, 0, len(e.FieldErrors))
+	for field := range e.FieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, field+": "+e.FieldErrors[field])
+	}
+	if e.Error != "" {
+		messages = append([]string{e.Error}, messages...)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// do sends body, if not nil, as JSON and decodes the data of the response
+// into data, if not nil.
+func (c *apiClient) do(ctx context.Context, method string, path string, body interface{}, data interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr apiError
+		err = json.NewDecoder(resp.Body).Decode(&apiErr)
+		if err != nil || apiErr.message() == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, apiErr.message())
+	}
+
+	if data == nil {
+		return nil
+	}
+	var res struct {
+		Data json.RawMessage `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return json.Unmarshal(res.Data, data)
+}
@@ -0,0 +1,91 @@
+// Command dqctl works with data quality checks from the command line.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	// timezones of time expressions resolve without tzdata on the host
+	_ "time/tzdata"
+)
+
+// command is a dqctl subcommand. run receives the arguments after the
+// subcommand name.
+type command struct {
+	summary string
+	run     func(args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"render":  {"Render a query with parameters without running it", render},
+	"run":     {"Run a check against a data gateway and evaluate its assertions", runCheck},
+	"queries": {"List, show, create and delete stored queries through the API", queries},
+	"suite":   {"Run the checks of a data product through the API and print its scorecard", suite},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "dqctl:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage(stdout)
+		return nil
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(os.Stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(args[1:], stdout)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: dqctl <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "dqctl <command> -h" for the flags of a command.`)
+}
+
+// readInput returns value, or the contents of the file at path if value is
+// empty. A path of "-" reads stdin.
+func readInput(value string, path string) (string, error) {
+	if value != "" && path != "" {
+		return "", errors.New("give either the value or a file, not both")
+	}
+	if path == "" {
+		return value, nil
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	return strings.TrimSpace(string(b)), err
+}
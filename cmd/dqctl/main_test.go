@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunCheck(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			SQL    string        `json:"sql"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		if req.SQL != "SELECT count(*) AS n FROM orders WHERE region = $1" || len(req.Params) != 1 || req.Params[0] != "eu" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"rows": []map[string]interface{}{{"n": 42}}}},
+		})
+	}))
+	defer gateway.Close()
+
+	args := []string{"run", "-gateway", gateway.URL, "-query", "SELECT count(*) AS n FROM orders WHERE region = $region", "-params", `{"region": "eu"}`}
+
+	var out bytes.Buffer
+	err := run(append(args, "-assertions", `[{"type": "range", "min": 1}]`), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"42", "value:  42", "status: PASS"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	err = run(append(args, "-assertions", `[{"type": "range", "max": 10}]`, "-o", "json"), &out)
+	if !errors.Is(err, errCheckFailed) {
+		t.Errorf("got error %v, want %v", err, errCheckFailed)
+	}
+	var result struct {
+		Value  float64 `json:"value"`
+		Status string  `json:"status"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || result.Value != 42 || result.Status != "FAIL" {
+		t.Errorf("unexpected JSON output %s", out.String())
+	}
+
+	err = run([]string{"run", "-gateway", gateway.URL, "-query", "DELETE FROM orders"}, &out)
+	if err == nil {
+		t.Error("expected the read-only guard to reject the query")
+	}
+}
+
+func TestQueriesAndSuite(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"Error": "Missing or invalid API key or token"})
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /queries":
+			if r.URL.Query().Get("data_product_id") != "6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"data": [{"query_id": "0b6f3a2e-1c2d-4e5f-8a9b-0c1d2e3f4a5b", "data_product_id": "6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11", "name": "orders_today", "weight": 1, "source": "sales.yaml"}]}`))
+		case "DELETE /queries/0b6f3a2e-1c2d-4e5f-8a9b-0c1d2e3f4a5b":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"Error": "Query is declared in the checks file sales.yaml and can only be changed there"}`))
+		case "POST /data-products/6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11/run":
+			w.Write([]byte(`{"data": {"data_product_id": "6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11", "score": 75, "dimensions": [{"dimension": "completeness", "score": 75, "weight": 2, "checks": 2}], "checks": [{"name": "orders_today", "dimension": "completeness", "weight": 1, "status": "WARN", "score": 50}]}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "list",
+			args: []string{"queries", "list", "-api", api.URL, "-api-key", "secret", "-data-product", "6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11"},
+			want: []string{"NAME", "orders_today", "sales.yaml"},
+		},
+		{
+			name:    "delete declared query",
+			args:    []string{"queries", "delete", "-api", api.URL, "-api-key", "secret", "0b6f3a2e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"},
+			wantErr: "409 Conflict: Query is declared in the checks file",
+		},
+		{
+			name:    "unauthorized",
+			args:    []string{"queries", "list", "-api", api.URL},
+			wantErr: "401 Unauthorized: Missing or invalid API key",
+		},
+		{
+			name: "suite",
+			args: []string{"suite", "-api", api.URL, "-api-key", "secret", "-data-product", "6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11"},
+			want: []string{"score:        75.0", "completeness  75.0", "orders_today  completeness  1       WARN    50.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(tt.args, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
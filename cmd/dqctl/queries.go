@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"xcaliber/data-quality-metrics-framework/internal/database"
+
+	"github.com/google/uuid"
+)
+
+var queriesCommands = map[string]func(args []string, stdout io.Writer) error{
+	"list":   listQueries,
+	"get":    getQuery,
+	"create": createQuery,
+	"delete": deleteQuery,
+}
+
+func queries(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: dqctl queries list|get|create|delete [flags]")
+	}
+	cmd, ok := queriesCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown queries command %q, want list, get, create or delete", args[0])
+	}
+	return cmd(args[1:], stdout)
+}
+
+func listQueries(args []string, stdout io.Writer) error {
+	var af apiFlags
+	fs := flag.NewFlagSet("queries list", flag.ContinueOnError)
+	af.register(fs)
+	dataProduct := fs.String("data-product", "", "only list the queries of this data product")
+	output := fs.String("o", "table", "output format: table or json")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+
+	path := "/queries"
+	if *dataProduct != "" {
+		path += "?data_product_id=" + url.QueryEscape(*dataProduct)
+	}
+	var list []database.Query
+	err = af.client().do(context.Background(), "GET", path, nil, &list)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return printJSON(stdout, list)
+	}
+	rows := make([][]string, 0, len(list))
+	for _, q := range list {
+		rows = append(rows, []string{q.QueryID.String(), q.DataProductID.String(), q.Name, orDash(q.Dimension), formatNumber(q.Weight), orDash(q.Source)})
+	}
+	return printTable(stdout, []string{"ID", "DATA PRODUCT", "NAME", "DIMENSION", "WEIGHT", "SOURCE"}, rows)
+}
+
+func getQuery(args []string, stdout io.Writer) error {
+	var af apiFlags
+	fs := flag.NewFlagSet("queries get", flag.ContinueOnError)
+	af.register(fs)
+	output := fs.String("o", "table", "output format: table or json")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	id, err := queryIDArg(fs)
+	if err != nil {
+		return err
+	}
+
+	var q database.Query
+	err = af.client().do(context.Background(), "GET", "/queries/"+id.String(), nil, &q)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return printJSON(stdout, q)
+	}
+	assertions, err := json.Marshal(q.Assertions)
+	if err != nil {
+		return err
+	}
+	return printTable(stdout, []string{"FIELD", "VALUE"}, [][]string{
+		{"id", q.QueryID.String()},
+		{"data product", q.DataProductID.String()},
+		{"name", q.Name},
+		{"description", orDash(q.Description)},
+		{"dimension", orDash(q.Dimension)},
+		{"weight", formatNumber(q.Weight)},
+		{"source", orDash(q.Source)},
+		{"default parameters", string(q.DefaultParameters)},
+		{"assertions", string(assertions)},
+		{"query", strings.Join(strings.Fields(q.Query), " ")},
+	})
+}
+
+func createQuery(args []string, stdout io.Writer) error {
+	var af apiFlags
+	fs := flag.NewFlagSet("queries create", flag.ContinueOnError)
+	af.register(fs)
+	file := fs.String("f", "", "file with the query as JSON, like the body of POST /queries; - for stdin")
+	output := fs.String("o", "table", "output format: table or json")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-f is required")
+	}
+
+	body, err := readInput("", *file)
+	if err != nil {
+		return err
+	}
+	if !json.Valid([]byte(body)) {
+		return fmt.Errorf("%s is not valid JSON", *file)
+	}
+
+	var q database.Query
+	err = af.client().do(context.Background(), "POST", "/queries", json.RawMessage(body), &q)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return printJSON(stdout, q)
+	}
+	fmt.Fprintf(stdout, "created query %s (%s)\n", q.QueryID, q.Name)
+	return nil
+}
+
+func deleteQuery(args []string, stdout io.Writer) error {
+	var af apiFlags
+	fs := flag.NewFlagSet("queries delete", flag.ContinueOnError)
+	af.register(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	id, err := queryIDArg(fs)
+	if err != nil {
+		return err
+	}
+
+	err = af.client().do(context.Background(), "DELETE", "/queries/"+id.String(), nil, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "deleted query %s\n", id)
+	return nil
+}
+
+// queryIDArg returns the query ID given as the only argument after the
+// flags.
+func queryIDArg(fs *flag.FlagSet) (uuid.UUID, error) {
+	if fs.NArg() != 1 {
+		return uuid.Nil, fmt.Errorf("usage: dqctl %s [flags] <query id>", fs.Name())
+	}
+	return uuid.Parse(fs.Arg(0))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
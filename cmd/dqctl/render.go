@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"xcaliber/data-quality-metrics-framework/internal/templates"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+// queryFlags are the flags of the commands that take a query or a check
+// template with parameters.
+type queryFlags struct {
+	query      string
+	queryFile  string
+	template   string
+	params     string
+	paramsFile string
+}
+
+func (f *queryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.query, "query", "", "SQL with $name placeholders")
+	fs.StringVar(&f.queryFile, "query-file", "", "file to read the SQL from, - for stdin")
+	fs.StringVar(&f.template, "template", "", "check template as JSON, instead of -query")
+	fs.StringVar(&f.params, "params", "", "parameters as a JSON object")
+	fs.StringVar(&f.paramsFile, "params-file", "", "file to read the parameters from")
+}
+
+// load returns the SQL of the query or template and its parameters,
+// including those the template adds.
+func (f *queryFlags) load() (string, json.RawMessage, error) {
+	sql, err := readInput(f.query, f.queryFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("query: %w", err)
+	}
+	parameters, err := readInput(f.params, f.paramsFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("parameters: %w", err)
+	}
+	if parameters == "" {
+		parameters = "{}"
+	}
+
+	rawParameters := json.RawMessage(parameters)
+	if f.template != "" {
+		if sql != "" {
+			return "", nil, errors.New("give either a query or a template, not both")
+		}
+		var spec templates.Spec
+		err = json.Unmarshal([]byte(f.template), &spec)
+		if err != nil {
+			return "", nil, fmt.Errorf("template: %w", err)
+		}
+		var templateParameters json.RawMessage
+		sql, templateParameters, err = spec.Render()
+		if err != nil {
+			return "", nil, fmt.Errorf("template: %w", err)
+		}
+		rawParameters, err = utility.MergeParameters(rawParameters, templateParameters)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if sql == "" {
+		return "", nil, errors.New("-query, -query-file or -template is required")
+	}
+
+	return sql, rawParameters, nil
+}
+
+// renderOutput is a rendering together with the verdict of the read-only
+// guard, which the server applies before running a query.
+type renderOutput struct {
+	*utility.Rendering
+	ReadOnlyError string `json:"read_only_error,omitempty"`
+}
+
+func render(args []string, stdout io.Writer) error {
+	var qf queryFlags
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	qf.register(fs)
+	bind := fs.Bool("bind", true, "bind parameters as $1, $2, ... instead of rendering them as literals")
+	output := fs.String("o", "text", "output format: text or json")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	sql, parameters, err := qf.load()
+	if err != nil {
+		return err
+	}
+
+	rendering, err := utility.RenderQuery(sql, parameters, *bind)
+	if err != nil {
+		return err
+	}
+	out := renderOutput{Rendering: rendering}
+	if err := utility.CheckReadOnly(sql, nil); err != nil {
+		out.ReadOnlyError = err.Error()
+	}
+
+	switch *output {
+	case "json":
+		return printJSON(stdout, out)
+	case "text":
+		printRendering(stdout, out)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+}
+
+func printRendering(w io.Writer, out renderOutput) {
+	fmt.Fprintln(w, out.SQL)
+	fmt.Fprintln(w)
+	for i, arg := range out.Args {
+		fmt.Fprintf(w, "$%d = %#v\n", i+1, arg)
+	}
+	if len(out.Args) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	list := func(names []string) string {
+		if len(names) == 0 {
+			return "-"
+		}
+		return strings.Join(names, ", ")
+	}
+	fmt.Fprintf(w, "substituted: %s\n", list(out.Substituted))
+	fmt.Fprintf(w, "unresolved:  %s\n", list(out.Unresolved))
+	fmt.Fprintf(w, "unused:      %s\n", list(out.Unused))
+	if out.ReadOnlyError != "" {
+		fmt.Fprintf(w, "rejected:    %s\n", out.ReadOnlyError)
+	}
+}
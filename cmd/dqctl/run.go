@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+// errCheckFailed makes dqctl exit with a non-zero status when a check
+// fails, for use in scripts.
+var errCheckFailed = errors.New("check failed")
+
+func runCheck(args []string, stdout io.Writer) error {
+	var qf queryFlags
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	qf.register(fs)
+	gatewayURL := fs.String("gateway", os.Getenv("DATA_GATEWAY_URL"), "data gateway URL, defaults to $DATA_GATEWAY_URL")
+	bind := fs.Bool("bind", true, "bind parameters as $1, $2, ... instead of rendering them as literals")
+	assertions := fs.String("assertions", "", "assertions as a JSON array")
+	labelColumns := fs.String("label-columns", "", "comma separated columns that label the series of value columns")
+	valueColumns := fs.String("value-columns", "", "comma separated columns with one value per row")
+	timeout := fs.Duration("timeout", datagateway.DefaultTimeout, "upper bound for the gateway call")
+	output := fs.String("o", "table", "output format: table or json")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	if *gatewayURL == "" {
+		return errors.New("-gateway or $DATA_GATEWAY_URL is required")
+	}
+
+	sql, parameters, err := qf.load()
+	if err != nil {
+		return err
+	}
+	query := database.Query{
+		Query:        sql,
+		Parameters:   parameters,
+		LabelColumns: splitList(*labelColumns),
+		ValueColumns: splitList(*valueColumns),
+	}
+	if *assertions != "" {
+		err = json.Unmarshal([]byte(*assertions), &query.Assertions)
+		if err != nil {
+			return fmt.Errorf("assertions: %w", err)
+		}
+		err = query.Assertions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the same guard, rendering and evaluation as on the server; there is
+	// no run history, so change_percent assertions pass
+	err = utility.CheckReadOnly(sql, nil)
+	if err != nil {
+		return err
+	}
+	queryStr, queryArgs, err := utility.PrepareQuery(sql, parameters, *bind)
+	if err != nil {
+		return err
+	}
+
+	client, err := datagateway.NewAuthenticatedHTTPClient(*timeout, gatewayAuth())
+	if err != nil {
+		return err
+	}
+	gateway := &datagateway.Gateway{URL: *gatewayURL, BindParameters: *bind, Client: client}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	rows, err := gateway.Query(ctx, queryStr, queryArgs)
+	if err != nil {
+		return err
+	}
+
+	result, err := runner.Evaluate(query, rows, nil)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		err = printJSON(stdout, result)
+	} else {
+		err = printResult(stdout, result)
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.Status == assertion.StatusFail {
+		return errCheckFailed
+	}
+	return nil
+}
+
+// gatewayAuth reads the gateway credentials from the environment variables
+// the server reads them from.
+func gatewayAuth() datagateway.AuthConfig {
+	auth := datagateway.AuthConfig{
+		BearerToken:  os.Getenv("DATA_GATEWAY_BEARER_TOKEN"),
+		APIKey:       os.Getenv("DATA_GATEWAY_API_KEY"),
+		APIKeyHeader: os.Getenv("DATA_GATEWAY_API_KEY_HEADER"),
+		CertFile:     os.Getenv("DATA_GATEWAY_TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("DATA_GATEWAY_TLS_KEY_FILE"),
+		CAFile:       os.Getenv("DATA_GATEWAY_TLS_CA_FILE"),
+	}
+	if tokenURL := os.Getenv("DATA_GATEWAY_OAUTH_TOKEN_URL"); tokenURL != "" {
+		auth.OAuth2 = &datagateway.OAuth2Config{
+			TokenURL:     tokenURL,
+			ClientID:     os.Getenv("DATA_GATEWAY_OAUTH_CLIENT_ID"),
+			ClientSecret: os.Getenv("DATA_GATEWAY_OAUTH_CLIENT_SECRET"),
+			Scopes:       strings.Fields(strings.ReplaceAll(os.Getenv("DATA_GATEWAY_OAUTH_SCOPES"), ",", " ")),
+		}
+	}
+	return auth
+}
+
+func printResult(w io.Writer, result *runner.Result) error {
+	err := printRows(w, result.Rows)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	switch {
+	case result.Value != nil:
+		fmt.Fprintf(w, "value:  %s\n", formatNumber(*result.Value))
+	case result.NoValue:
+		fmt.Fprintln(w, "value:  NULL")
+	case result.ValueError != "" && len(result.Series) == 0:
+		fmt.Fprintf(w, "value:  %s\n", result.ValueError)
+	}
+	for _, series := range result.Series {
+		fmt.Fprintf(w, "series: %s %v = %s %s\n", series.Column, series.Labels, formatNumber(series.Value), series.Status)
+	}
+	if result.Status != "" {
+		fmt.Fprintf(w, "status: %s\n", result.Status)
+	}
+	for _, a := range result.Assertions {
+		fmt.Fprintf(w, "  %s %s %s\n", a.Status, a.Assertion.Type, a.Message)
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"xcaliber/data-quality-metrics-framework/internal/scorecard"
+
+	"github.com/google/uuid"
+)
+
+func suite(args []string, stdout io.Writer) error {
+	var af apiFlags
+	fs := flag.NewFlagSet("suite", flag.ContinueOnError)
+	af.register(fs)
+	dataProduct := fs.String("data-product", "", "data product whose checks to run")
+	params := fs.String("params", "", "parameters as a JSON object, merged over the defaults of every check")
+	output := fs.String("o", "table", "output format: table or json")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	id, err := uuid.Parse(*dataProduct)
+	if err != nil {
+		return errors.New("-data-product must be a data product ID")
+	}
+
+	body := map[string]interface{}{}
+	if *params != "" {
+		if !json.Valid([]byte(*params)) {
+			return errors.New("-params is not valid JSON")
+		}
+		body["parameters"] = json.RawMessage(*params)
+	}
+
+	var card scorecard.Scorecard
+	err = af.client().do(context.Background(), "POST", "/data-products/"+id.String()+"/run", body, &card)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return printJSON(stdout, card)
+	}
+	return printScorecard(stdout, card)
+}
+
+func printScorecard(w io.Writer, card scorecard.Scorecard) error {
+	fmt.Fprintf(w, "data product: %s\n", card.DataProductID)
+	fmt.Fprintf(w, "score:        %s\n\n", score(card.Score))
+
+	rows := make([][]string, 0, len(card.Dimensions))
+	for _, d := range card.Dimensions {
+		rows = append(rows, []string{d.Dimension, score(d.Score), formatNumber(d.Weight), fmt.Sprint(d.Checks)})
+	}
+	err := printTable(w, []string{"DIMENSION", "SCORE", "WEIGHT", "CHECKS"}, rows)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	rows = make([][]string, 0, len(card.Checks))
+	for _, c := range card.Checks {
+		rows = append(rows, []string{c.Name, orDash(c.Dimension), formatNumber(c.Weight), orDash(string(c.Status)), score(c.Score), orDash(c.Error)})
+	}
+	return printTable(w, []string{"CHECK", "DIMENSION", "WEIGHT", "STATUS", "SCORE", "ERROR"}, rows)
+}
+
+func score(s *float64) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", *s)
+}
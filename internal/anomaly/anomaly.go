@@ -0,0 +1,211 @@
+package anomaly
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	TypeZScore   = "zscore"
+	TypeMAD      = "mad"
+	TypeSeasonal = "seasonal"
+)
+
+const (
+	defaultWindow     = 30
+	defaultThreshold  = 3
+	defaultMinSamples = 5
+
+	// madScale makes the median absolute deviation comparable to a standard
+	// deviation for normally distributed values.
+	madScale = 1.4826
+)
+
+// Model describes how a query's value is compared with its history.
+//
+//   - zscore: mean and standard deviation of the last Window runs
+//   - mad: median and median absolute deviation of the last Window runs
+//   - seasonal: mean and standard deviation of runs on the same day of the
+//     week over the last Window weeks
+//
+// A value is anomalous when it lies more than Threshold deviations from the
+// expected value. No verdict is given until MinSamples runs are available.
+type Model struct {
+	Type       string  `json:"type"`
+	Window     int     `json:"window,omitempty"`
+	Threshold  float64 `json:"threshold,omitempty"`
+	MinSamples int     `json:"min_samples,omitempty"`
+	Severity   string  `json:"severity,omitempty"`
+}
+
+func (m Model) Validate() error {
+	switch m.Type {
+	case TypeZScore, TypeMAD, TypeSeasonal:
+	default:
+		return fmt.Errorf("unsupported anomaly model %q", m.Type)
+	}
+	if m.Window < 0 || m.Threshold < 0 || m.MinSamples < 0 {
+		return errors.New("anomaly window, threshold and min_samples must not be negative")
+	}
+	switch m.Severity {
+	case "", "warn", "fail":
+	default:
+		return fmt.Errorf("unsupported anomaly severity %q", m.Severity)
+	}
+	return nil
+}
+
+// Defaults returns m with unset fields filled in.
+func (m Model) Defaults() Model {
+	if m.Window == 0 {
+		m.Window = defaultWindow
+	}
+	if m.Threshold == 0 {
+		m.Threshold = defaultThreshold
+	}
+	if m.MinSamples == 0 {
+		m.MinSamples = defaultMinSamples
+	}
+	if m.Severity == "" {
+		m.Severity = "warn"
+	}
+	return m
+}
+
+// Since returns the oldest run time the model needs history for, or the
+// zero time when the model works on a number of runs instead.
+func (m Model) Since(at time.Time) time.Time {
+	m = m.Defaults()
+	if m.Type == TypeSeasonal {
+		return at.AddDate(0, 0, -7*m.Window)
+	}
+	return time.Time{}
+}
+
+type Point struct {
+	Value float64
+	At    time.Time
+}
+
+type Result struct {
+	Model     string  `json:"model"`
+	Expected  float64 `json:"expected"`
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+	Score     float64 `json:"score"`
+	Anomalous bool    `json:"anomalous"`
+	Samples   int     `json:"samples"`
+}
+
+// Evaluate compares value, observed at at, with history, which must be
+// ordered newest first. It reports false when there is not enough history.
+func (m Model) Evaluate(value float64, at time.Time, history []Point) (*Result, bool) {
+	m = m.Defaults()
+
+	var samples []float64
+	switch m.Type {
+	case TypeSeasonal:
+		since := m.Since(at)
+		for _, p := range history {
+			if p.At.Before(since) {
+				break
+			}
+			if p.At.In(at.Location()).Weekday() == at.Weekday() {
+				samples = append(samples, p.Value)
+			}
+		}
+	default:
+		for _, p := range history {
+			if len(samples) == m.Window {
+				break
+			}
+			samples = append(samples, p.Value)
+		}
+	}
+
+	if len(samples) < m.MinSamples {
+		return nil, false
+	}
+
+	var center, spread float64
+	switch m.Type {
+	case TypeMAD:
+		center = median(samples)
+		deviations := make([]float64, len(samples))
+		for i, v := range samples {
+			deviations[i] = math.Abs(v - center)
+		}
+		spread = madScale * median(deviations)
+	default:
+		center, spread = meanStddev(samples)
+	}
+
+	result := &Result{
+		Model:    m.Type,
+		Expected: center,
+		Lower:    center - m.Threshold*spread,
+		Upper:    center + m.Threshold*spread,
+		Samples:  len(samples),
+	}
+	if spread == 0 {
+		// a constant history, any change is a deviation
+		result.Anomalous = value != center
+	} else {
+		result.Score = (value - center) / spread
+		result.Anomalous = math.Abs(result.Score) > m.Threshold
+	}
+
+	return result, true
+}
+
+func meanStddev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func (m *Model) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
+
+func (m *Model) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("cannot scan %T into Model", src)
+	}
+}
@@ -0,0 +1,115 @@
+package anomaly_test
+
+import (
+	"math"
+	"testing"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/anomaly"
+)
+
+// history returns one point per day before at, newest first.
+func history(at time.Time, values ...float64) []anomaly.Point {
+	points := make([]anomaly.Point, len(values))
+	for i, v := range values {
+		points[i] = anomaly.Point{Value: v, At: at.AddDate(0, 0, -(i + 1))}
+	}
+	return points
+}
+
+func TestEvaluate(t *testing.T) {
+	at := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		name          string
+		model         anomaly.Model
+		value         float64
+		history       []anomaly.Point
+		wantOK        bool
+		wantAnomalous bool
+		wantExpected  float64
+	}{
+		{
+			name:    "not enough history",
+			model:   anomaly.Model{Type: anomaly.TypeZScore},
+			value:   10,
+			history: history(at, 10, 11),
+			wantOK:  false,
+		},
+		{
+			name:         "zscore within band",
+			model:        anomaly.Model{Type: anomaly.TypeZScore, MinSamples: 4},
+			value:        11,
+			history:      history(at, 10, 12, 10, 12),
+			wantOK:       true,
+			wantExpected: 11,
+		},
+		{
+			name:          "zscore outside band",
+			model:         anomaly.Model{Type: anomaly.TypeZScore, MinSamples: 4},
+			value:         20,
+			history:       history(at, 10, 12, 10, 12),
+			wantOK:        true,
+			wantAnomalous: true,
+			wantExpected:  11,
+		},
+		{
+			name:          "zscore only uses window",
+			model:         anomaly.Model{Type: anomaly.TypeZScore, Window: 4, MinSamples: 4},
+			value:         100,
+			history:       history(at, 10, 12, 10, 12, 100, 100, 100),
+			wantOK:        true,
+			wantAnomalous: true,
+			wantExpected:  11,
+		},
+		{
+			name:          "constant history",
+			model:         anomaly.Model{Type: anomaly.TypeZScore, MinSamples: 3},
+			value:         6,
+			history:       history(at, 5, 5, 5),
+			wantOK:        true,
+			wantAnomalous: true,
+			wantExpected:  5,
+		},
+		{
+			name:         "mad ignores outliers in history",
+			model:        anomaly.Model{Type: anomaly.TypeMAD, MinSamples: 5},
+			value:        11,
+			history:      history(at, 10, 11, 12, 11, 1000),
+			wantOK:       true,
+			wantExpected: 11,
+		},
+		{
+			name:  "seasonal compares the same weekday",
+			model: anomaly.Model{Type: anomaly.TypeSeasonal, MinSamples: 2},
+			value: 100,
+			// Mondays are 7 and 14 days back and carry the high weekly volume
+			history: history(at,
+				10, 10, 10, 10, 10, 10, 101,
+				10, 10, 10, 10, 10, 10, 99,
+			),
+			wantOK:       true,
+			wantExpected: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := tt.model.Evaluate(tt.value, at, tt.history)
+			if ok != tt.wantOK {
+				t.Fatalf("Evaluate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if result.Anomalous != tt.wantAnomalous {
+				t.Errorf("Evaluate() anomalous = %v, want %v (%+v)", result.Anomalous, tt.wantAnomalous, result)
+			}
+			if math.Abs(result.Expected-tt.wantExpected) > 1e-9 {
+				t.Errorf("Evaluate() expected = %v, want %v", result.Expected, tt.wantExpected)
+			}
+			if result.Lower > result.Expected || result.Upper < result.Expected {
+				t.Errorf("Evaluate() band [%v, %v] does not contain %v", result.Lower, result.Upper, result.Expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package assertion
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Code is the numeric value the status is exported as.
+func (s Status) Code() float64 {
+	switch s {
+	case StatusWarn:
+		return 1
+	case StatusFail:
+		return 2
+	default:
+		return 0
+	}
+}
+
+const (
+	TypeRange         = "range"
+	TypeEquals        = "equals"
+	TypeNonZero       = "non_zero"
+	TypeChangePercent = "change_percent"
+)
+
+const (
+	SeverityWarn = "warn"
+	SeverityFail = "fail"
+)
+
+// Assertion is a condition the value returned by a query must satisfy.
+//
+//   - range: Min <= value <= Max, either bound may be omitted
+//   - equals: value == Value
+//   - non_zero: value != 0
+//   - change_percent: value is within Percent % of the previous run
+//
+// A violated assertion fails the check unless its Severity is "warn".
+type Assertion struct {
+	Type     string   `json:"type"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	Value    *float64 `json:"value,omitempty"`
+	Percent  *float64 `json:"percent,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+}
+
+func (a Assertion) Validate() error {
+	switch a.Type {
+	case TypeRange:
+		if a.Min == nil && a.Max == nil {
+			return errors.New("range assertion requires min or max")
+		}
+		if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+			return errors.New("range assertion min must not be greater than max")
+		}
+	case TypeEquals:
+		if a.Value == nil {
+			return errors.New("equals assertion requires value")
+		}
+	case TypeNonZero:
+	case TypeChangePercent:
+		if a.Percent == nil || *a.Percent < 0 {
+			return errors.New("change_percent assertion requires a non-negative percent")
+		}
+	default:
+		return fmt.Errorf("unsupported assertion type %q", a.Type)
+	}
+
+	switch a.Severity {
+	case "", SeverityWarn, SeverityFail:
+	default:
+		return fmt.Errorf("unsupported assertion severity %q", a.Severity)
+	}
+
+	return nil
+}
+
+type Result struct {
+	Assertion Assertion `json:"assertion"`
+	Status    Status    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// Assertions is stored as a jsonb column.
+type Assertions []Assertion
+
+func (a Assertions) Validate() error {
+	for i, assertion := range a {
+		err := assertion.Validate()
+		if err != nil {
+			return fmt.Errorf("assertion %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Evaluate checks value against every assertion. previous is the value of
+// the last run, or nil when there is none, in which case change_percent
+// assertions pass. The overall status is the worst individual status.
+func (a Assertions) Evaluate(value float64, previous *float64) (Status, []Result) {
+	status := StatusPass
+	results := make([]Result, 0, len(a))
+
+	for _, assertion := range a {
+		message := assertion.check(value, previous)
+
+		result := Result{Assertion: assertion, Status: StatusPass, Message: message}
+		if message != "" {
+			result.Status = StatusFail
+			if assertion.Severity == SeverityWarn {
+				result.Status = StatusWarn
+			}
+		}
+		if result.Status.Code() > status.Code() {
+			status = result.Status
+		}
+		results = append(results, result)
+	}
+
+	return status, results
+}
+
+// check returns a description of the violation, or an empty string when the
+// assertion holds.
+func (a Assertion) check(value float64, previous *float64) string {
+	switch a.Type {
+	case TypeRange:
+		if a.Min != nil && value < *a.Min {
+			return fmt.Sprintf("value %v is less than minimum %v", value, *a.Min)
+		}
+		if a.Max != nil && value > *a.Max {
+			return fmt.Sprintf("value %v is greater than maximum %v", value, *a.Max)
+		}
+	case TypeEquals:
+		if value != *a.Value {
+			return fmt.Sprintf("value %v does not equal %v", value, *a.Value)
+		}
+	case TypeNonZero:
+		if value == 0 {
+			return "value is zero"
+		}
+	case TypeChangePercent:
+		if previous == nil {
+			return ""
+		}
+		if *previous == 0 {
+			if value != 0 {
+				return fmt.Sprintf("value changed from 0 to %v", value)
+			}
+			return ""
+		}
+		change := math.Abs(value-*previous) / math.Abs(*previous) * 100
+		if change > *a.Percent {
+			return fmt.Sprintf("value %v changed %.2f%% from previous value %v, more than %v%%", value, change, *previous, *a.Percent)
+		}
+	}
+	return ""
+}
+
+func (a Assertions) Value() (driver.Value, error) {
+	if a == nil {
+		a = Assertions{}
+	}
+	js, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
+
+func (a *Assertions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = Assertions{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into Assertions", src)
+	}
+}
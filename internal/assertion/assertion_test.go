@@ -0,0 +1,117 @@
+package assertion_test
+
+import (
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		assertions assertion.Assertions
+		value      float64
+		previous   *float64
+		want       assertion.Status
+	}{
+		{
+			name:       "no assertions",
+			assertions: assertion.Assertions{},
+			value:      10,
+			want:       assertion.StatusPass,
+		},
+		{
+			name:       "within range",
+			assertions: assertion.Assertions{{Type: assertion.TypeRange, Min: ptr(1), Max: ptr(10)}},
+			value:      10,
+			want:       assertion.StatusPass,
+		},
+		{
+			name:       "below minimum",
+			assertions: assertion.Assertions{{Type: assertion.TypeRange, Min: ptr(1)}},
+			value:      0,
+			want:       assertion.StatusFail,
+		},
+		{
+			name:       "above maximum with warn severity",
+			assertions: assertion.Assertions{{Type: assertion.TypeRange, Max: ptr(5), Severity: assertion.SeverityWarn}},
+			value:      6,
+			want:       assertion.StatusWarn,
+		},
+		{
+			name: "worst status wins",
+			assertions: assertion.Assertions{
+				{Type: assertion.TypeRange, Max: ptr(5), Severity: assertion.SeverityWarn},
+				{Type: assertion.TypeEquals, Value: ptr(3)},
+			},
+			value: 6,
+			want:  assertion.StatusFail,
+		},
+		{
+			name:       "non zero",
+			assertions: assertion.Assertions{{Type: assertion.TypeNonZero}},
+			value:      0,
+			want:       assertion.StatusFail,
+		},
+		{
+			name:       "change percent without previous run",
+			assertions: assertion.Assertions{{Type: assertion.TypeChangePercent, Percent: ptr(10)}},
+			value:      100,
+			want:       assertion.StatusPass,
+		},
+		{
+			name:       "change percent within bound",
+			assertions: assertion.Assertions{{Type: assertion.TypeChangePercent, Percent: ptr(10)}},
+			value:      109,
+			previous:   ptr(100),
+			want:       assertion.StatusPass,
+		},
+		{
+			name:       "change percent exceeded",
+			assertions: assertion.Assertions{{Type: assertion.TypeChangePercent, Percent: ptr(10)}},
+			value:      85,
+			previous:   ptr(100),
+			want:       assertion.StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, results := tt.assertions.Evaluate(tt.value, tt.previous)
+			if status != tt.want {
+				t.Errorf("Evaluate() status = %v, want %v (%+v)", status, tt.want, results)
+			}
+			if len(results) != len(tt.assertions) {
+				t.Errorf("Evaluate() returned %d results, expected %d", len(results), len(tt.assertions))
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion assertion.Assertion
+		wantErr   bool
+	}{
+		{name: "range without bounds", assertion: assertion.Assertion{Type: assertion.TypeRange}, wantErr: true},
+		{name: "inverted range", assertion: assertion.Assertion{Type: assertion.TypeRange, Min: ptr(2), Max: ptr(1)}, wantErr: true},
+		{name: "equals without value", assertion: assertion.Assertion{Type: assertion.TypeEquals}, wantErr: true},
+		{name: "change percent without percent", assertion: assertion.Assertion{Type: assertion.TypeChangePercent}, wantErr: true},
+		{name: "unknown type", assertion: assertion.Assertion{Type: "median"}, wantErr: true},
+		{name: "unknown severity", assertion: assertion.Assertion{Type: assertion.TypeNonZero, Severity: "info"}, wantErr: true},
+		{name: "valid", assertion: assertion.Assertion{Type: assertion.TypeRange, Min: ptr(0), Severity: assertion.SeverityWarn}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.assertion.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKey is an entry of the API keys file. Keys are stored as the hex
+// SHA-256 hash of the key, never in plain text.
+type APIKey struct {
+	Name         string   `json:"name"`
+	KeySHA256    string   `json:"key_sha256"`
+	Role         Role     `json:"role"`
+	DataProducts []string `json:"data_products"`
+}
+
+// LoadAPIKeys reads a JSON array of API keys from path.
+func LoadAPIKeys(path string) (map[[sha256.Size]byte]*Principal, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	err = json.Unmarshal(file, &keys)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse API keys file: %w", err)
+	}
+
+	principals := make(map[[sha256.Size]byte]*Principal, len(keys))
+	for i, key := range keys {
+		p, hash, err := key.principal()
+		if err != nil {
+			return nil, fmt.Errorf("API key %d (%s): %w", i, key.Name, err)
+		}
+		if _, exists := principals[hash]; exists {
+			return nil, fmt.Errorf("API key %d (%s): duplicate key", i, key.Name)
+		}
+		principals[hash] = p
+	}
+
+	return principals, nil
+}
+
+func (k APIKey) principal() (*Principal, [sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+
+	if strings.TrimSpace(k.Name) == "" {
+		return nil, hash, fmt.Errorf("name is required")
+	}
+	b, err := hex.DecodeString(k.KeySHA256)
+	if err != nil || len(b) != sha256.Size {
+		return nil, hash, fmt.Errorf("key_sha256 must be a hex encoded SHA-256 hash")
+	}
+	copy(hash[:], b)
+
+	if !k.Role.Valid() {
+		return nil, hash, fmt.Errorf("unknown role %q", k.Role)
+	}
+	all, ids, err := parseDataProducts(k.DataProducts)
+	if err != nil {
+		return nil, hash, err
+	}
+
+	return &Principal{Subject: k.Name, Role: k.Role, AllDataProducts: all, DataProducts: ids}, hash, nil
+}
@@ -0,0 +1,164 @@
+// Package auth authenticates API callers with API keys or JWTs and decides
+// which data products they may read, run or change.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoCredentials is returned for requests without an API key or token.
+	ErrNoCredentials = errors.New("authentication required")
+	// ErrInvalidCredentials is returned for unknown API keys and for tokens
+	// that are malformed, expired or not signed by a trusted key.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// APIKeyHeader is the header API keys are sent in. They are also accepted
+// as bearer tokens.
+const APIKeyHeader = "X-API-Key"
+
+// Role is what a caller may do. Every role includes the ones below it.
+type Role string
+
+const (
+	// RoleViewer may read queries, runs and configuration.
+	RoleViewer Role = "viewer"
+	// RoleRunner may also run queries.
+	RoleRunner Role = "runner"
+	// RoleAdmin may also create, change and delete queries, schedules, data
+	// sources and notification channels.
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleRunner: 2,
+	RoleAdmin:  3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Includes reports whether r grants everything required grants.
+func (r Role) Includes(required Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[required]
+}
+
+// AllDataProducts in a list of data products grants access to every data
+// product.
+const AllDataProducts = "*"
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	Role    Role
+	// AllDataProducts is set when the caller is not limited to
+	// DataProducts.
+	AllDataProducts bool
+	DataProducts    []uuid.UUID
+}
+
+// Anonymous is the principal of every request when authentication is
+// disabled.
+var Anonymous = &Principal{Subject: "anonymous", Role: RoleAdmin, AllDataProducts: true}
+
+// CanAccess reports whether p may act on the data product with role.
+func (p *Principal) CanAccess(dataProductID uuid.UUID, role Role) bool {
+	if !p.Role.Includes(role) {
+		return false
+	}
+	if p.AllDataProducts {
+		return true
+	}
+	for _, id := range p.DataProducts {
+		if id == dataProductID {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDataProducts converts the data products of an API key or token.
+func parseDataProducts(values []string) (all bool, ids []uuid.UUID, err error) {
+	for _, v := range values {
+		if v == AllDataProducts {
+			all = true
+			continue
+		}
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid data product %q: %w", v, err)
+		}
+		ids = append(ids, id)
+	}
+	return all, ids, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok
+}
+
+// Authenticator verifies the API key or JWT of a request.
+type Authenticator struct {
+	// APIKeys are indexed by the SHA-256 hash of the key.
+	APIKeys map[[sha256.Size]byte]*Principal
+	// JWT verifies bearer tokens; nil if tokens are not accepted.
+	JWT *JWTVerifier
+}
+
+// Enabled reports whether any credentials are configured.
+func (a *Authenticator) Enabled() bool {
+	return len(a.APIKeys) > 0 || a.JWT != nil
+}
+
+// Authenticate returns the principal of the API key in the X-API-Key header
+// or of the bearer token in the Authorization header. Bearer tokens that
+// are not JWTs are looked up as API keys.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.apiKey(key)
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return nil, ErrNoCredentials
+	}
+	token = strings.TrimSpace(token)
+
+	if strings.Count(token, ".") == 2 && a.JWT != nil {
+		return a.JWT.Verify(token)
+	}
+	return a.apiKey(token)
+}
+
+func (a *Authenticator) apiKey(key string) (*Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	// compare against every key in constant time rather than looking the
+	// hash up, so the response time does not reveal partial matches
+	for h, p := range a.APIKeys {
+		if subtle.ConstantTimeCompare(h[:], hash[:]) == 1 {
+			return p, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
@@ -0,0 +1,231 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	productA = uuid.MustParse("6f1c8a52-6c47-4a7e-9a52-0f3e3c0a1a01")
+	productB = uuid.MustParse("6f1c8a52-6c47-4a7e-9a52-0f3e3c0a1a02")
+)
+
+func writeFile(t *testing.T, name string, v interface{}) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	err = os.WriteFile(path, b, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func hash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+func TestPrincipalCanAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		product   uuid.UUID
+		role      auth.Role
+		want      bool
+	}{
+		{"viewer reads own product", auth.Principal{Role: auth.RoleViewer, DataProducts: []uuid.UUID{productA}}, productA, auth.RoleViewer, true},
+		{"viewer cannot run", auth.Principal{Role: auth.RoleViewer, DataProducts: []uuid.UUID{productA}}, productA, auth.RoleRunner, false},
+		{"runner runs own product", auth.Principal{Role: auth.RoleRunner, DataProducts: []uuid.UUID{productA}}, productA, auth.RoleRunner, true},
+		{"runner cannot edit", auth.Principal{Role: auth.RoleRunner, DataProducts: []uuid.UUID{productA}}, productA, auth.RoleAdmin, false},
+		{"admin of other product", auth.Principal{Role: auth.RoleAdmin, DataProducts: []uuid.UUID{productA}}, productB, auth.RoleViewer, false},
+		{"admin of every product", auth.Principal{Role: auth.RoleAdmin, AllDataProducts: true}, productB, auth.RoleAdmin, true},
+		{"no role", auth.Principal{AllDataProducts: true}, productA, auth.RoleViewer, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.CanAccess(tt.product, tt.role); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	path := writeFile(t, "keys.json", []auth.APIKey{
+		{Name: "team-a", KeySHA256: hash("secret-a"), Role: auth.RoleRunner, DataProducts: []string{productA.String()}},
+		{Name: "platform", KeySHA256: hash("secret-admin"), Role: auth.RoleAdmin, DataProducts: []string{"*"}},
+	})
+	keys, err := auth.LoadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &auth.Authenticator{APIKeys: keys}
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		subject string
+		wantErr error
+	}{
+		{"header", auth.APIKeyHeader, "secret-a", "team-a", nil},
+		{"bearer", "Authorization", "Bearer secret-admin", "platform", nil},
+		{"unknown key", auth.APIKeyHeader, "secret-b", "", auth.ErrInvalidCredentials},
+		{"no credentials", "", "", "", auth.ErrNoCredentials},
+		{"basic auth", "Authorization", "Basic c2VjcmV0LWE=", "", auth.ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/queries", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			p, err := a.Authenticate(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && p.Subject != tt.subject {
+				t.Errorf("got subject %q, want %q", p.Subject, tt.subject)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeysInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []auth.APIKey
+	}{
+		{"plain text key", []auth.APIKey{{Name: "a", KeySHA256: "secret", Role: auth.RoleViewer}}},
+		{"unknown role", []auth.APIKey{{Name: "a", KeySHA256: hash("a"), Role: "owner"}}},
+		{"invalid data product", []auth.APIKey{{Name: "a", KeySHA256: hash("a"), Role: auth.RoleViewer, DataProducts: []string{"sales"}}}},
+		{"duplicate key", []auth.APIKey{
+			{Name: "a", KeySHA256: hash("a"), Role: auth.RoleViewer},
+			{Name: "b", KeySHA256: hash("a"), Role: auth.RoleAdmin},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.LoadAPIKeys(writeFile(t, "keys.json", tt.keys))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	path := writeFile(t, "jwks.json", map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "oct", "kid": "hmac", "k": base64.RawURLEncoding.EncodeToString(secret)},
+			{
+				"kty": "RSA",
+				"kid": "rsa",
+				"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+		},
+	})
+	verifier, err := auth.LoadJWKS(path, "https://idp.example.com", "dq-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(modify func(*auth.Claims)) *auth.Claims {
+		c := &auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "team-a",
+				Issuer:    "https://idp.example.com",
+				Audience:  jwt.ClaimStrings{"dq-metrics"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Role:         auth.RoleRunner,
+			DataProducts: []string{productA.String()},
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, c *auth.Claims) string {
+		token := jwt.NewWithClaims(method, c)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"HS256", sign(jwt.SigningMethodHS256, "hmac", secret, claims(nil)), false},
+		{"RS256", sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)), false},
+		{"wrong secret", sign(jwt.SigningMethodHS256, "hmac", []byte("fedcba9876543210fedcba9876543210"), claims(nil)), true},
+		{"RS256 with symmetric key", sign(jwt.SigningMethodRS256, "hmac", rsaKey, claims(nil)), true},
+		{"unknown kid", sign(jwt.SigningMethodHS256, "other", secret, claims(nil)), true},
+		{"expired", sign(jwt.SigningMethodHS256, "hmac", secret, claims(func(c *auth.Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})), true},
+		{"no expiry", sign(jwt.SigningMethodHS256, "hmac", secret, claims(func(c *auth.Claims) { c.ExpiresAt = nil })), true},
+		{"wrong issuer", sign(jwt.SigningMethodHS256, "hmac", secret, claims(func(c *auth.Claims) { c.Issuer = "https://evil.example.com" })), true},
+		{"wrong audience", sign(jwt.SigningMethodHS256, "hmac", secret, claims(func(c *auth.Claims) { c.Audience = jwt.ClaimStrings{"other"} })), true},
+		{"unknown role", sign(jwt.SigningMethodHS256, "hmac", secret, claims(func(c *auth.Claims) { c.Role = "owner" })), true},
+		{"none algorithm", sign(jwt.SigningMethodNone, "hmac", jwt.UnsafeAllowNoneSignatureType, claims(nil)), true},
+	}
+
+	a := &auth.Authenticator{JWT: verifier}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/run", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			p, err := a.Authenticate(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidCredentials) {
+					t.Errorf("got error %v, want %v", err, auth.ErrInvalidCredentials)
+				}
+				return
+			}
+			if p.Subject != "team-a" || p.Role != auth.RoleRunner || !p.CanAccess(productA, auth.RoleRunner) || p.CanAccess(productB, auth.RoleViewer) {
+				t.Errorf("unexpected principal %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the claims of an API token. Role and DataProducts grant the
+// same permissions as the fields of an API key.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role         Role     `json:"role"`
+	DataProducts []string `json:"data_products"`
+}
+
+// jwk is a key of a JSON Web Key Set. Only the fields of RSA and symmetric
+// keys are read.
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	K       string `json:"k"`
+}
+
+// JWTVerifier verifies HS256 and RS256 tokens against the keys of a local
+// JWKS file. Symmetric ("oct") keys verify HS256 tokens and RSA keys verify
+// RS256 tokens, so a token cannot pick an algorithm its key was not meant
+// for.
+type JWTVerifier struct {
+	hmacKeys map[string][]byte
+	rsaKeys  map[string]*rsa.PublicKey
+	options  []jwt.ParserOption
+}
+
+// LoadJWKS reads the JSON Web Key Set at path. Tokens must carry an
+// expiry and, when issuer or audience are set, match them.
+func LoadJWKS(path string, issuer string, audience string) (*JWTVerifier, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.Unmarshal(file, &set)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse JWKS file: %w", err)
+	}
+
+	v := &JWTVerifier{
+		hmacKeys: map[string][]byte{},
+		rsaKeys:  map[string]*rsa.PublicKey{},
+		options: []jwt.ParserOption{
+			jwt.WithValidMethods([]string{"HS256", "RS256"}),
+			jwt.WithExpirationRequired(),
+		},
+	}
+	if issuer != "" {
+		v.options = append(v.options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		v.options = append(v.options, jwt.WithAudience(audience))
+	}
+
+	for i, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		switch key.KeyType {
+		case "oct":
+			k, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil || len(k) < 32 {
+				return nil, fmt.Errorf("JWKS key %d: k must be a base64url encoded secret of at least 32 bytes", i)
+			}
+			v.hmacKeys[key.KeyID] = k
+		case "RSA":
+			k, err := rsaPublicKey(key.N, key.E)
+			if err != nil {
+				return nil, fmt.Errorf("JWKS key %d: %w", i, err)
+			}
+			v.rsaKeys[key.KeyID] = k
+		default:
+			return nil, fmt.Errorf("JWKS key %d: unsupported key type %q", i, key.KeyType)
+		}
+	}
+
+	if len(v.hmacKeys) == 0 && len(v.rsaKeys) == 0 {
+		return nil, errors.New("JWKS file has no signing keys")
+	}
+
+	return v, nil
+}
+
+func rsaPublicKey(n string, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil || len(nb) == 0 {
+		return nil, errors.New("invalid RSA modulus")
+	}
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil || len(eb) == 0 || len(eb) > 4 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(new(big.Int).SetBytes(eb).Int64())}
+	if key.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+// Verify checks the signature and claims of token and returns its
+// principal.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, v.key, v.options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	if !claims.Role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidCredentials, claims.Role)
+	}
+	all, ids, err := parseDataProducts(claims.DataProducts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	return &Principal{Subject: claims.Subject, Role: claims.Role, AllDataProducts: all, DataProducts: ids}, nil
+}
+
+// key selects the verification key by the kid header of the token. Tokens
+// without kid are accepted only if there is a single key for the algorithm.
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	switch token.Method.Alg() {
+	case "HS256":
+		return lookup(v.hmacKeys, kid)
+	case "RS256":
+		return lookup(v.rsaKeys, kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+func lookup[K any](keys map[string]K, kid string) (K, error) {
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	var zero K
+	return zero, fmt.Errorf("unknown key %q", kid)
+}
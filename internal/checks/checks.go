@@ -0,0 +1,199 @@
+// Package checks loads the checks declared in YAML files, so they can be
+// kept in version control and synced into the query catalog.
+package checks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"xcaliber/data-quality-metrics-framework/internal/anomaly"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is a check declared in a file. The fields are those of a
+// stored query, with the default parameters under parameters, the
+// assertions that act as its thresholds and an optional schedule.
+type Definition struct {
+	Name          string               `json:"name"`
+	DataProductID uuid.UUID            `json:"data_product_id"`
+	Description   string               `json:"description"`
+	Query         string               `json:"query"`
+	Template      *templates.Spec      `json:"template"`
+	Parameters    json.RawMessage      `json:"parameters"`
+	Assertions    assertion.Assertions `json:"assertions"`
+	LabelColumns  []string             `json:"label_columns"`
+	ValueColumns  []string             `json:"value_columns"`
+	Anomaly       *anomaly.Model       `json:"anomaly"`
+	Dimension     string               `json:"dimension"`
+	Weight        *float64             `json:"weight"`
+	Schedule      *Schedule            `json:"schedule"`
+
+	// Source is the file the check is declared in, relative to the
+	// directory it was loaded from.
+	Source string `json:"-"`
+}
+
+// Schedule runs a check periodically, see workflow.Scheduler.
+type Schedule struct {
+	Cron       string          `json:"cron"`
+	Parameters json.RawMessage `json:"parameters"`
+	Paused     bool            `json:"paused"`
+}
+
+// Key identifies a check in the catalog: names are unique per data
+// product.
+func (d Definition) Key() string {
+	return d.DataProductID.String() + "/" + d.Name
+}
+
+// Load reads the checks declared in the .yaml and .yml files in dir and
+// its subdirectories, in file name order. A file may declare several
+// checks as separate YAML documents. Load fails if any file cannot be
+// parsed or a check is declared twice.
+func Load(dir string) ([]Definition, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var (
+		definitions []Definition
+		declared    = map[string]string{}
+	)
+	for _, path := range files {
+		source, err := filepath.Rel(dir, path)
+		if err != nil {
+			source = path
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		defs, err := Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		for _, def := range defs {
+			def.Source = source
+			if other, ok := declared[def.Key()]; ok {
+				return nil, fmt.Errorf("%s: check %q of data product %s is already declared in %s", source, def.Name, def.DataProductID, other)
+			}
+			declared[def.Key()] = source
+			definitions = append(definitions, def)
+		}
+	}
+
+	return definitions, nil
+}
+
+// Parse decodes the checks in the YAML documents of b. Unknown fields are
+// rejected so that typos do not silently drop thresholds.
+func Parse(b []byte) ([]Definition, error) {
+	var definitions []Definition
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for i := 1; ; i++ {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc, err := nodeValue(&node)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if doc == nil {
+			continue
+		}
+
+		// YAML is converted to JSON so that the JSON decoding of the
+		// query fields applies as is
+		j, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		jdec := json.NewDecoder(bytes.NewReader(j))
+		jdec.DisallowUnknownFields()
+
+		var def Definition
+		err = jdec.Decode(&def)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		definitions = append(definitions, def)
+	}
+
+	return definitions, nil
+}
+
+// nodeValue decodes node like yaml.Unmarshal into an interface{} does, but
+// keeps timestamps as written since parameters are passed on as strings.
+func nodeValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return nodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be strings", key.Line)
+			}
+			v, err := nodeValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		l := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			v, err := nodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	}
+
+	if node.ShortTag() == "!!timestamp" {
+		return node.Value, nil
+	}
+	var v interface{}
+	err := node.Decode(&v)
+	return v, err
+}
@@ -0,0 +1,118 @@
+package checks_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/checks"
+)
+
+const ordersChecks = `
+name: orders_freshness
+data_product_id: 6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11
+template:
+  type: freshness
+  table: sales.orders
+  column: created_at
+assertions:
+  - type: range
+    max: 3600
+schedule:
+  cron: "*/15 * * * *"
+---
+name: orders_since
+data_product_id: 6f1c8a52-3d4e-4b0a-9d61-0c2f4d9c1a11
+query: SELECT count(*) FROM sales.orders WHERE created_at >= $start
+parameters:
+  start: 2024-06-01
+  limit: 10
+weight: 2
+`
+
+func TestParse(t *testing.T) {
+	defs, err := checks.Parse([]byte(ordersChecks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("got %d checks, want 2", len(defs))
+	}
+
+	freshness := defs[0]
+	if freshness.Template == nil || freshness.Template.Column != "created_at" {
+		t.Errorf("template not decoded: %+v", freshness.Template)
+	}
+	if len(freshness.Assertions) != 1 || *freshness.Assertions[0].Max != 3600 {
+		t.Errorf("assertions not decoded: %+v", freshness.Assertions)
+	}
+	if freshness.Schedule == nil || freshness.Schedule.Cron != "*/15 * * * *" {
+		t.Errorf("schedule not decoded: %+v", freshness.Schedule)
+	}
+
+	// dates are passed on as written
+	since := defs[1]
+	if got := string(since.Parameters); got != `{"limit":10,"start":"2024-06-01"}` {
+		t.Errorf("got parameters %s", got)
+	}
+	if since.Weight == nil || *since.Weight != 2 {
+		t.Errorf("got weight %v, want 2", since.Weight)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":   "name: x\nthreshold: 3\n",
+		"invalid yaml":    "name: [x\n",
+		"invalid uuid":    "name: x\ndata_product_id: nope\n",
+		"non-string keys": "name: x\nparameters:\n  [a]: 1\n",
+	}
+
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := checks.Parse([]byte(doc))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "sales", "orders.yaml"), ordersChecks)
+	writeFile(t, filepath.Join(dir, "customers.yml"), "name: customers\ndata_product_id: 0b6f3a2e-1c2d-4e5f-8a9b-0c1d2e3f4a5b\nquery: SELECT 1\n")
+	writeFile(t, filepath.Join(dir, "README.md"), "not a check")
+
+	defs, err := checks.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, def := range defs {
+		got = append(got, def.Source+":"+def.Name)
+	}
+	want := "customers.yml:customers sales/orders.yaml:orders_freshness sales/orders.yaml:orders_since"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+
+	writeFile(t, filepath.Join(dir, "copy.yaml"), ordersChecks)
+	_, err = checks.Load(dir)
+	if err == nil || !strings.Contains(err.Error(), "already declared in copy.yaml") {
+		t.Errorf("got error %v, want a duplicate check", err)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,251 @@
+package datagateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthConfig holds the credentials sent to the data gateway. At most one of
+// BearerToken, APIKey and OAuth2 is used; client certificates for mTLS can
+// be combined with any of them.
+type AuthConfig struct {
+	BearerToken string
+
+	APIKey       string
+	APIKeyHeader string
+
+	OAuth2 *OAuth2Config
+
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// OAuth2Config configures the client credentials grant.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+const defaultAPIKeyHeader = "X-API-Key"
+
+// tokenExpiryMargin renews OAuth2 tokens this long before they expire so
+// that they do not expire in flight.
+const tokenExpiryMargin = 30 * time.Second
+
+// NewAuthenticatedHTTPClient returns a client like NewHTTPClient that
+// authenticates every request with auth.
+func NewAuthenticatedHTTPClient(timeout time.Duration, auth AuthConfig) (*http.Client, error) {
+	client := NewHTTPClient(timeout)
+	transport := client.Transport.(*http.Transport)
+
+	if auth.CertFile != "" || auth.CAFile != "" {
+		tlsConfig, err := auth.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var credentials credentials
+	switch {
+	case auth.OAuth2 != nil:
+		if auth.OAuth2.TokenURL == "" || auth.OAuth2.ClientID == "" {
+			return nil, errors.New("oauth2 requires a token url and a client id")
+		}
+		credentials = &clientCredentials{
+			config: *auth.OAuth2,
+			// the token endpoint gets the same TLS settings but no credentials
+			client: &http.Client{Transport: transport, Timeout: timeout},
+		}
+	case auth.BearerToken != "":
+		credentials = staticHeader{name: "Authorization", value: "Bearer " + auth.BearerToken}
+	case auth.APIKey != "":
+		header := auth.APIKeyHeader
+		if header == "" {
+			header = defaultAPIKeyHeader
+		}
+		credentials = staticHeader{name: header, value: auth.APIKey}
+	}
+
+	if credentials != nil {
+		client.Transport = &authTransport{base: transport, credentials: credentials}
+	}
+	return client, nil
+}
+
+func (auth AuthConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if auth.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(auth.CertFile, auth.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if auth.CAFile != "" {
+		pem, err := os.ReadFile(auth.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", auth.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+type credentials interface {
+	apply(ctx context.Context, req *http.Request) error
+	// invalidate discards cached credentials the gateway rejected and
+	// reports whether new ones can be fetched.
+	invalidate() bool
+}
+
+// authTransport adds credentials to every request. Requests rejected with
+// 401 are retried once with fresh credentials when those can be fetched.
+type authTransport struct {
+	base        http.RoundTripper
+	credentials credentials
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.roundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.GetBody == nil || !t.credentials.invalidate() {
+		return resp, err
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return t.roundTrip(retry)
+}
+
+func (t *authTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	err := t.credentials.apply(req.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+type staticHeader struct {
+	name  string
+	value string
+}
+
+func (h staticHeader) apply(ctx context.Context, req *http.Request) error {
+	req.Header.Set(h.name, h.value)
+	return nil
+}
+
+func (h staticHeader) invalidate() bool {
+	return false
+}
+
+// clientCredentials fetches OAuth2 access tokens with the client
+// credentials grant and caches them until shortly before they expire.
+type clientCredentials struct {
+	config OAuth2Config
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (c *clientCredentials) apply(ctx context.Context, req *http.Request) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (c *clientCredentials) invalidate() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+	return true
+}
+
+func (c *clientCredentials) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.expires.IsZero() || time.Now().Before(c.expires)) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error creating token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("got status code: %v from token endpoint : %v", resp.StatusCode, string(body))
+	}
+
+	var token tokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("error decoding access token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+
+	c.token = token.AccessToken
+	c.expires = time.Time{}
+	if token.ExpiresIn > 0 {
+		c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin)
+	}
+	return c.token, nil
+}
@@ -0,0 +1,113 @@
+package datagateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
+)
+
+// gatewayServer accepts requests whose header matches want and rejects the
+// others with 401.
+func gatewayServer(header string, want func() string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(header) != want() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"rows": []map[string]interface{}{{"count": 1}}}},
+		})
+	}))
+}
+
+func TestStaticAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   datagateway.AuthConfig
+		header string
+		value  string
+	}{
+		{"bearer token", datagateway.AuthConfig{BearerToken: "secret"}, "Authorization", "Bearer secret"},
+		{"api key", datagateway.AuthConfig{APIKey: "secret"}, "X-API-Key", "secret"},
+		{"api key header", datagateway.AuthConfig{APIKey: "secret", APIKeyHeader: "Api-Key"}, "Api-Key", "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gatewayServer(tt.header, func() string { return tt.value })
+			defer server.Close()
+
+			client, err := datagateway.NewAuthenticatedHTTPClient(time.Minute, tt.auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gateway := &datagateway.Gateway{URL: server.URL, Client: client}
+			_, err = gateway.Query(context.Background(), "SELECT 1", nil)
+			if err != nil {
+				t.Errorf("query failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read query" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	// the gateway only accepts the most recently issued token
+	gateway := gatewayServer("Authorization", func() string { return fmt.Sprintf("Bearer token-%d", issued.Load()) })
+	defer gateway.Close()
+
+	client, err := datagateway.NewAuthenticatedHTTPClient(time.Minute, datagateway.AuthConfig{
+		OAuth2: &datagateway.OAuth2Config{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Scopes:       []string{"read", "query"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &datagateway.Gateway{URL: gateway.URL, Client: client}
+
+	for i := 0; i < 3; i++ {
+		_, err = g.Query(context.Background(), "SELECT 1", nil)
+		if err != nil {
+			t.Fatalf("query %d failed: %v", i, err)
+		}
+	}
+	if got := issued.Load(); got != 1 {
+		t.Errorf("got %d tokens issued, want the token to be cached", got)
+	}
+
+	// a revoked token is replaced
+	issued.Add(1)
+	_, err = g.Query(context.Background(), "SELECT 1", nil)
+	if err != nil {
+		t.Fatalf("query with revoked token failed: %v", err)
+	}
+	if got := issued.Load(); got != 3 {
+		t.Errorf("got %d tokens issued, want 3", got)
+	}
+}
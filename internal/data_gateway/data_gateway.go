@@ -2,57 +2,156 @@ package datagateway
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
-type responseBody struct {
-	Results []struct {
-		Rows []map[string]interface{} `json:"rows"`
-	} `json:"results"`
+// DefaultTimeout bounds gateway calls of gateways without a Client and of
+// clients from NewHTTPClient(0).
+const DefaultTimeout = 5 * time.Minute
+
+var defaultClient = NewHTTPClient(DefaultTimeout)
+
+// DataSource executes a query with positional ($1, $2, ...) parameters and
+// returns its rows. Implementations abandon the query when ctx is done.
+type DataSource interface {
+	Query(ctx context.Context, query string, params []interface{}) ([]map[string]interface{}, error)
+}
+
+// NewHTTPClient returns a client for gateway calls that keeps connections
+// to the gateway open between calls. timeout bounds every call, including
+// reading the response; zero uses DefaultTimeout.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 100
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
+// Gateway is the HTTP data gateway. Unless BindParameters is set, callers
+// render parameters into the SQL text and pass nil params. Client should be
+// shared between gateways; nil uses a client with DefaultTimeout. Breaker
+// is optional and should be shared by every Gateway with the same URL.
+// Responses beyond Limits are rejected.
+type Gateway struct {
+	URL            string
+	BindParameters bool
+	Client         *http.Client
+	Retry          RetryPolicy
+	Breaker        *Breaker
+	Limits         Limits
 }
 
 func RunQuery(dataGatewayUrl string, query string) ([]map[string]interface{}, error) {
+	return RunQueryWithParams(dataGatewayUrl, query, nil)
+}
+
+// RunQueryWithParams sends query with positional ($1, $2, ...) bind
+// parameters to the data gateway. When params is nil the query is sent
+// as-is.
+func RunQueryWithParams(dataGatewayUrl string, query string, params []interface{}) ([]map[string]interface{}, error) {
+	gateway := &Gateway{URL: dataGatewayUrl}
+	return gateway.Query(context.Background(), query, params)
+}
+
+// Query runs query on the gateway, retrying transient failures according
+// to g.Retry.
+func (g *Gateway) Query(ctx context.Context, query string, params []interface{}) ([]map[string]interface{}, error) {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if g.Breaker != nil && !g.Breaker.Allow() {
+			if lastErr != nil {
+				// the breaker opened between retries, keep the failure
+				// that opened it
+				return nil, fmt.Errorf("%w: %w", ErrCircuitOpen, lastErr)
+			}
+			return nil, ErrCircuitOpen
+		}
+
+		rows, err := g.query(ctx, query, params)
+		if err != nil && ctx.Err() != nil {
+			// the caller gave up, which says nothing about the gateway
+			return nil, err
+		}
+		if g.Breaker != nil {
+			g.Breaker.Record(err)
+		}
+		if err == nil || !IsRetryable(err) || attempt >= g.Retry.MaxAttempts {
+			return rows, err
+		}
+
+		lastErr = err
+
+		sleepErr := sleep(ctx, g.Retry.delay(attempt, err))
+		if sleepErr != nil {
+			return nil, err
+		}
+	}
+}
+
+func (g *Gateway) query(ctx context.Context, query string, params []interface{}) ([]map[string]interface{}, error) {
 
 	payload := map[string]interface{}{
 		"sql": query,
 	}
+	if params != nil {
+		payload["params"] = params
+	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling payload: %v", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, dataGatewayUrl, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.Client
+	if client == nil {
+		client = defaultClient
+	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making POST request: %v", err)
+		return nil, fmt.Errorf("error making POST request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
-	}
-
 	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("got status code: %v from data gateway : %v", resp.StatusCode, string(bodyBytes))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, defaultMaxErrorBytes))
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
-	response := responseBody{}
-	err = json.Unmarshal([]byte(bodyBytes), &response)
+	resultSets, err := decodeResults(resp.Body, g.Limits)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+		if errors.Is(err, ErrNoResultSets) || errors.Is(err, ErrTooManyRows) || errors.Is(err, ErrResponseTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
-	return response.Results[0].Rows, nil
+
+	// statements before the query, such as SET, produce result sets of
+	// their own, the query's rows are in the last one
+	return resultSets[len(resultSets)-1], nil
 
 }
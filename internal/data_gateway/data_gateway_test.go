@@ -1,11 +1,13 @@
 package datagateway_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
 )
 
@@ -134,3 +136,78 @@ func TestRunQueryInvalidURL(t *testing.T) {
 		t.Errorf("Received different error: %v", err)
 	}
 }
+
+func TestRunQueryWithParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			SQL    string        `json:"sql"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+			return
+		}
+		if reqBody.SQL != "SELECT * FROM table WHERE id = $1" {
+			t.Errorf("unexpected sql %q", reqBody.SQL)
+		}
+		if len(reqBody.Params) != 1 || reqBody.Params[0] != "abc" {
+			t.Errorf("unexpected params %v", reqBody.Params)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"rows": []map[string]interface{}{{"count": 1}}}},
+		})
+	}))
+	defer server.Close()
+
+	rows, err := datagateway.RunQueryWithParams(server.URL, "SELECT * FROM table WHERE id = $1", []interface{}{"abc"})
+	if err != nil {
+		t.Fatalf("RunQueryWithParams() error = %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("RunQueryWithParams() returned %d rows, expected 1", len(rows))
+	}
+}
+
+func TestGatewayCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a hung gateway
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tests := []struct {
+		name    string
+		gateway *datagateway.Gateway
+		timeout time.Duration
+	}{
+		{
+			name:    "context deadline",
+			gateway: &datagateway.Gateway{URL: server.URL, Client: datagateway.NewHTTPClient(time.Minute)},
+			timeout: 50 * time.Millisecond,
+		},
+		{
+			name:    "client timeout",
+			gateway: &datagateway.Gateway{URL: server.URL, Client: datagateway.NewHTTPClient(50 * time.Millisecond)},
+			timeout: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			start := time.Now()
+			_, err := tt.gateway.Query(ctx, "SELECT 1", nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("query returned after %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package datagateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrNoResultSets is returned when the gateway response has no result
+	// sets, for example because the statement produced none.
+	ErrNoResultSets = errors.New("data gateway returned no result sets")
+
+	ErrTooManyRows      = errors.New("data gateway response exceeds the maximum number of rows")
+	ErrResponseTooLarge = errors.New("data gateway response exceeds the maximum size")
+	errUnexpectedFormat = errors.New("unexpected data gateway response format")
+)
+
+// defaultMaxErrorBytes is how much of an error response is kept for the
+// error message.
+const defaultMaxErrorBytes = 4096
+
+// Limits bounds the gateway responses that are decoded. Zero values mean no
+// limit.
+type Limits struct {
+	MaxRows  int
+	MaxBytes int64
+}
+
+// decodeResults reads the result sets of a gateway response of the form
+// {"results": [{"rows": [...]}, ...]} row by row, without holding more than
+// the decoded rows in memory.
+func decodeResults(body io.Reader, limits Limits) ([][]map[string]interface{}, error) {
+	if limits.MaxBytes > 0 {
+		body = &limitedReader{r: body, remaining: limits.MaxBytes}
+	}
+	dec := json.NewDecoder(body)
+	// keep numbers as they were sent, float64 would round large integers
+	dec.UseNumber()
+
+	var resultSets [][]map[string]interface{}
+	rows := 0
+
+	err := decodeObject(dec, func(key string) error {
+		if key != "results" {
+			return skipValue(dec)
+		}
+		return decodeArray(dec, func() error {
+			resultSet := []map[string]interface{}{}
+			err := decodeObject(dec, func(key string) error {
+				if key != "rows" {
+					return skipValue(dec)
+				}
+				return decodeArray(dec, func() error {
+					rows++
+					if limits.MaxRows > 0 && rows > limits.MaxRows {
+						return ErrTooManyRows
+					}
+					var row map[string]interface{}
+					err := dec.Decode(&row)
+					if err != nil {
+						return err
+					}
+					resultSet = append(resultSet, row)
+					return nil
+				})
+			})
+			resultSets = append(resultSets, resultSet)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resultSets) == 0 {
+		return nil, ErrNoResultSets
+	}
+	return resultSets, nil
+}
+
+// decodeObject calls value for every key of the next JSON object, which
+// must consume the key's value.
+func decodeObject(dec *json.Decoder, value func(key string) error) error {
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return errUnexpectedFormat
+		}
+		err = value(key)
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeArray calls element for every element of the next JSON array,
+// which must consume the element. null is treated as an empty array.
+func decodeArray(dec *json.Decoder, element func() error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return errUnexpectedFormat
+	}
+	for dec.More() {
+		err = element()
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("%w: expected %v, got %v", errUnexpectedFormat, delim, token)
+	}
+	return nil
+}
+
+// skipValue consumes the next JSON value without keeping it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// limitedReader fails with ErrResponseTooLarge instead of silently
+// truncating the response like io.LimitReader.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// a single byte more tells a body of exactly the limit from a larger one
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,111 @@
+package datagateway_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
+)
+
+func TestGatewayDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		limits   datagateway.Limits
+		wantRows int
+		wantErr  error
+		wantAny  bool
+	}{
+		{
+			name:     "single result set",
+			body:     `{"results": [{"rows": [{"n": 1}, {"n": 2}]}]}`,
+			wantRows: 2,
+		},
+		{
+			name:     "multiple result sets use the last",
+			body:     `{"results": [{"rows": []}, {"rows": [{"n": 1}, {"n": 2}, {"n": 3}]}]}`,
+			wantRows: 3,
+		},
+		{
+			name:     "unknown keys are skipped",
+			body:     `{"meta": {"took": [1, {"a": 2}]}, "results": [{"columns": ["n"], "rows": [{"n": 1}], "count": 1}]}`,
+			wantRows: 1,
+		},
+		{
+			name:     "null rows",
+			body:     `{"results": [{"rows": null}]}`,
+			wantRows: 0,
+		},
+		{
+			name:    "no result sets",
+			body:    `{"results": []}`,
+			wantErr: datagateway.ErrNoResultSets,
+		},
+		{
+			name:    "missing results",
+			body:    `{"error": null}`,
+			wantErr: datagateway.ErrNoResultSets,
+		},
+		{
+			name:    "too many rows",
+			body:    `{"results": [{"rows": [{"n": 1}]}, {"rows": [{"n": 2}, {"n": 3}]}]}`,
+			limits:  datagateway.Limits{MaxRows: 2},
+			wantErr: datagateway.ErrTooManyRows,
+		},
+		{
+			name:    "too large",
+			body:    `{"results": [{"rows": [{"n": "` + strings.Repeat("x", 1000) + `"}]}]}`,
+			limits:  datagateway.Limits{MaxBytes: 100},
+			wantErr: datagateway.ErrResponseTooLarge,
+		},
+		{
+			name:     "exactly at the byte limit",
+			body:     `{"results": [{"rows": [{"n": 1}]}]}`,
+			limits:   datagateway.Limits{MaxBytes: int64(len(`{"results": [{"rows": [{"n": 1}]}]}`))},
+			wantRows: 1,
+		},
+		{
+			name:    "malformed",
+			body:    `{"results": [{"rows": [{"n": 1}`,
+			wantAny: true,
+		},
+		{
+			name:    "unexpected shape",
+			body:    `{"results": {"rows": []}}`,
+			wantAny: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			gateway := &datagateway.Gateway{URL: server.URL, Limits: tt.limits}
+			rows, err := gateway.Query(context.Background(), "SELECT 1", nil)
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got err %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAny:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(rows) != tt.wantRows {
+					t.Errorf("got %d rows, want %d", len(rows), tt.wantRows)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package datagateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the gateway while the circuit
+// breaker considers it down.
+var ErrCircuitOpen = errors.New("data gateway circuit breaker is open")
+
+// StatusError is a non-2xx response from the gateway.
+type StatusError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by a Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("got status code: %v from data gateway : %v", e.StatusCode, e.Body)
+}
+
+// IsRetryable reports whether err is a transient gateway failure: a 5xx or
+// 429 response, a dropped or refused connection, a client timeout or an
+// open circuit. Errors in the query itself, such as 4xx responses, other
+// transport failures, such as TLS errors, and cancellation by the caller
+// are not. A timeout cannot be told apart from the expired deadline of the
+// caller by the error alone, callers check their context first.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsPermanent reports whether the gateway rejected the query itself or
+// returned a result that cannot be used, so running it again cannot
+// succeed.
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrNoResultSets) || errors.Is(err, ErrTooManyRows) || errors.Is(err, ErrResponseTooLarge) {
+		return true
+	}
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && !IsRetryable(err)
+}
+
+// RetryPolicy retries transient failures with exponential backoff and full
+// jitter. A Retry-After header overrides the computed delay, up to
+// MaxDelay, so a gateway cannot park a worker for longer. The zero value
+// makes a single attempt.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// delay returns how long to wait before the given retry, counting from 1.
+func (p RetryPolicy) delay(retry int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if p.MaxDelay > 0 && statusErr.RetryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return statusErr.RetryAfter
+	}
+
+	d := p.BaseDelay << (retry - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// Breaker stops calls to a gateway after Threshold consecutive transient
+// failures. Once Cooldown has passed a single trial call is let through;
+// its success closes the breaker again, its failure reopens it.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// Allow reports whether a call may be made.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Threshold <= 0 || b.failures < b.Threshold {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.Cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// Record updates the breaker with the outcome of a call. Only transient
+// failures count, a gateway that rejects a query is still up.
+func (b *Breaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	switch {
+	case IsRetryable(err):
+		b.failures++
+		if b.failures >= b.Threshold {
+			b.openedAt = time.Now()
+		}
+	case err == nil || IsPermanent(err):
+		b.failures = 0
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
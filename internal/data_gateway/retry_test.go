@@ -0,0 +1,262 @@
+package datagateway_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantRetryable bool
+		wantPermanent bool
+	}{
+		{"server error", &datagateway.StatusError{StatusCode: 502}, true, false},
+		{"rate limited", &datagateway.StatusError{StatusCode: 429}, true, false},
+		{"bad query", &datagateway.StatusError{StatusCode: 400}, false, true},
+		{"wrapped bad query", fmt.Errorf("run: %w", &datagateway.StatusError{StatusCode: 422}), false, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true, false},
+		{"client timeout", &url.Error{Op: "Post", URL: "http://gateway", Err: &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}}, true, false},
+		{"tls", &url.Error{Op: "Post", URL: "https://gateway", Err: x509.UnknownAuthorityError{}}, false, false},
+		{"unsupported scheme", &url.Error{Op: "Post", URL: "ftp://gateway", Err: errors.New("unsupported protocol scheme")}, false, false},
+		{"circuit open", datagateway.ErrCircuitOpen, true, false},
+		{"no result sets", datagateway.ErrNoResultSets, false, true},
+		{"too many rows", datagateway.ErrTooManyRows, false, true},
+		{"cancelled", context.Canceled, false, false},
+		{"other", errors.New("error unmarshalling JSON"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datagateway.IsRetryable(tt.err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.wantRetryable)
+			}
+			if got := datagateway.IsPermanent(tt.err); got != tt.wantPermanent {
+				t.Errorf("IsPermanent() = %v, want %v", got, tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestGatewayRetry(t *testing.T) {
+	ok := map[string]interface{}{
+		"results": []map[string]interface{}{{"rows": []map[string]interface{}{{"count": 1}}}},
+	}
+
+	tests := []struct {
+		name         string
+		statusCodes  []int
+		retryAfter   string
+		wantAttempts int32
+		wantErr      bool
+		minElapsed   time.Duration
+		maxElapsed   time.Duration
+		maxDelay     time.Duration
+	}{
+		{"recovers after server errors", []int{503, 502, 200}, "", 3, false, 0, 0, 10 * time.Millisecond},
+		{"gives up after max attempts", []int{503, 503, 503, 200}, "", 3, true, 0, 0, 10 * time.Millisecond},
+		{"does not retry bad queries", []int{400, 200}, "", 1, true, 0, 0, 10 * time.Millisecond},
+		{"honors retry-after", []int{429, 200}, "1", 2, false, time.Second, 0, 2 * time.Second},
+		{"caps retry-after at the max delay", []int{503, 200}, "3600", 2, false, 0, time.Second, 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statusCodes[attempts.Add(1)-1]
+				if status != http.StatusOK {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(status)
+					return
+				}
+				json.NewEncoder(w).Encode(ok)
+			}))
+			defer server.Close()
+
+			gateway := &datagateway.Gateway{
+				URL:   server.URL,
+				Retry: datagateway.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: tt.maxDelay},
+			}
+
+			start := time.Now()
+			rows, err := gateway.Query(context.Background(), "SELECT 1", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(rows) != 1 {
+				t.Errorf("got %d rows, want 1", len(rows))
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", got, tt.wantAttempts)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.minElapsed {
+				t.Errorf("retried after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+			if tt.maxElapsed > 0 && elapsed > tt.maxElapsed {
+				t.Errorf("retried after %v, want at most %v", elapsed, tt.maxElapsed)
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	b := &datagateway.Breaker{Threshold: 2, Cooldown: 50 * time.Millisecond}
+	down := &datagateway.StatusError{StatusCode: 503}
+
+	b.Record(down)
+	if !b.Allow() {
+		t.Fatal("breaker opened before reaching the threshold")
+	}
+	b.Record(&datagateway.StatusError{StatusCode: 400})
+	b.Record(down)
+	if !b.Allow() {
+		t.Fatal("a rejected query must reset the failure count")
+	}
+
+	b.Record(down)
+	if b.Allow() {
+		t.Fatal("breaker is closed after reaching the threshold")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("breaker does not allow a trial call after the cooldown")
+	}
+	if b.Allow() {
+		t.Fatal("breaker allows a second call while the trial is running")
+	}
+
+	b.Record(nil)
+	if !b.Allow() {
+		t.Fatal("breaker stays open after a successful trial")
+	}
+}
+
+func TestGatewayCircuitOpen(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	gateway := &datagateway.Gateway{
+		URL:     server.URL,
+		Breaker: &datagateway.Breaker{Threshold: 2, Cooldown: time.Minute},
+	}
+
+	for i := 0; i < 4; i++ {
+		gateway.Query(context.Background(), "SELECT 1", nil)
+	}
+
+	_, err := gateway.Query(context.Background(), "SELECT 1", nil)
+	if !errors.Is(err, datagateway.ErrCircuitOpen) {
+		t.Errorf("got err %v, want ErrCircuitOpen", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("gateway was called %d times, want 2", got)
+	}
+}
+
+func TestGatewayTLSFailure(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+	}))
+	defer server.Close()
+
+	// the default client does not trust the certificate of the test server
+	breaker := &datagateway.Breaker{Threshold: 1, Cooldown: time.Minute}
+	gateway := &datagateway.Gateway{
+		URL:     server.URL,
+		Retry:   datagateway.RetryPolicy{MaxAttempts: 3},
+		Breaker: breaker,
+	}
+
+	_, err := gateway.Query(context.Background(), "SELECT 1", nil)
+	if err == nil {
+		t.Fatal("got no error from an untrusted certificate")
+	}
+	if datagateway.IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = true, want false", err)
+	}
+	if !breaker.Allow() {
+		t.Error("a TLS failure opened the breaker")
+	}
+	if got := attempts.Load(); got != 0 {
+		t.Errorf("handler was called %d times, want 0", got)
+	}
+}
+
+func TestGatewayCallerDeadline(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	breaker := &datagateway.Breaker{Threshold: 1, Cooldown: time.Minute}
+	gateway := &datagateway.Gateway{
+		URL:     server.URL,
+		Retry:   datagateway.RetryPolicy{MaxAttempts: 3},
+		Breaker: breaker,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := gateway.Query(ctx, "SELECT 1", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got err %v, want context.DeadlineExceeded", err)
+	}
+	if !breaker.Allow() {
+		t.Error("the deadline of the caller opened the breaker")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("gateway was called %d times, want 1", got)
+	}
+}
+
+func TestGatewayCircuitOpensDuringRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	gateway := &datagateway.Gateway{
+		URL:     server.URL,
+		Retry:   datagateway.RetryPolicy{MaxAttempts: 3},
+		Breaker: &datagateway.Breaker{Threshold: 1, Cooldown: time.Minute},
+	}
+
+	_, err := gateway.Query(context.Background(), "SELECT 1", nil)
+	if !errors.Is(err, datagateway.ErrCircuitOpen) {
+		t.Errorf("got err %v, want ErrCircuitOpen", err)
+	}
+	var statusErr *datagateway.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got err %v, want it to wrap the 503 that opened the breaker", err)
+	}
+}
@@ -0,0 +1,229 @@
+package datagateway
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TypeGateway  = "gateway"
+	TypePostgres = "postgres"
+	TypeSQLite   = "sqlite"
+	TypeDuckDB   = "duckdb"
+)
+
+// drivers maps data source types to database/sql driver names.
+var drivers = map[string]string{
+	TypePostgres: "postgres",
+	TypeSQLite:   "sqlite3",
+	TypeDuckDB:   "duckdb",
+}
+
+// Config locates a data source: URL for gateways, DSN for databases. For
+// SQLite and DuckDB the DSN is the path of the database file.
+type Config struct {
+	URL            string `json:"url,omitempty"`
+	DSN            string `json:"dsn,omitempty"`
+	BindParameters *bool  `json:"bind_parameters,omitempty"`
+}
+
+// Validate checks a data source definition without connecting to it.
+func Validate(sourceType string, config json.RawMessage) error {
+	_, err := parseConfig(sourceType, config)
+	return err
+}
+
+// redacted replaces secrets in configs returned by Redact.
+const redacted = "xxxxx"
+
+// keywordPasswordRX matches the password of a key=value connection string
+// such as "host=db user=dq password='secret'".
+var keywordPasswordRX = regexp.MustCompile(`(?i)\b(\w*password)\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redact returns config with the passwords in its url and dsn and the
+// values of fields that look like secrets replaced, so that it can be
+// shown to users who may run the data source but not see its credentials.
+// A config that cannot be decoded is redacted completely.
+func Redact(config json.RawMessage) json.RawMessage {
+	var fields map[string]interface{}
+	err := json.Unmarshal(config, &fields)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+
+	for key, value := range fields {
+		s, ok := value.(string)
+		switch {
+		case !ok:
+		case secretName(key):
+			fields[key] = redacted
+		case key == "url" || key == "dsn":
+			fields[key] = redactDSN(s)
+		}
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+	return b
+}
+
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range []string{"pass", "secret", "token", "api_key", "apikey"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactDSN redacts the password of a URL or key=value connection string
+// and the query parameters that look like secrets.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return keywordPasswordRX.ReplaceAllString(dsn, "${1}="+redacted)
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	query := u.Query()
+	for key := range query {
+		if secretName(key) {
+			query.Set(key, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func parseConfig(sourceType string, config json.RawMessage) (Config, error) {
+	var cfg Config
+	err := json.Unmarshal(config, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid data source config: %w", err)
+	}
+
+	if sourceType == TypeGateway {
+		if cfg.URL == "" {
+			return cfg, errors.New("gateway data source requires url")
+		}
+		return cfg, nil
+	}
+
+	driverName, ok := drivers[sourceType]
+	if !ok {
+		return cfg, fmt.Errorf("unsupported data source type %q", sourceType)
+	}
+	if !slices.Contains(sql.Drivers(), driverName) {
+		return cfg, fmt.Errorf("%s data sources are not supported by this build, no %q driver is registered", sourceType, driverName)
+	}
+	if cfg.DSN == "" {
+		return cfg, fmt.Errorf("%s data source requires dsn", sourceType)
+	}
+	return cfg, nil
+}
+
+// Sources hands out data sources, keeping one connection pool per distinct
+// database so that runs share connections. Default is used for data
+// products without a data source of their own. Gateway data sources use
+// HTTPClient, Retry and Limits, and share a circuit breaker per URL when
+// BreakerThreshold is set.
+type Sources struct {
+	Default          DataSource
+	HTTPClient       *http.Client
+	Retry            RetryPolicy
+	Limits           Limits
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	mu       sync.Mutex
+	open     map[string]DataSource
+	breakers map[string]*Breaker
+}
+
+func (s *Sources) Get(sourceType string, config json.RawMessage) (DataSource, error) {
+	cfg, err := parseConfig(sourceType, config)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sourceType == TypeGateway {
+		gateway := &Gateway{
+			URL:            cfg.URL,
+			BindParameters: true,
+			Client:         s.HTTPClient,
+			Retry:          s.Retry,
+			Breaker:        s.breaker(cfg.URL),
+			Limits:         s.Limits,
+		}
+		if cfg.BindParameters != nil {
+			gateway.BindParameters = *cfg.BindParameters
+		}
+		return gateway, nil
+	}
+
+	key := sourceType + "\x00" + cfg.DSN
+
+	if source, ok := s.open[key]; ok {
+		return source, nil
+	}
+
+	source, err := OpenSQL(drivers[sourceType], cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if s.open == nil {
+		s.open = map[string]DataSource{}
+	}
+	s.open[key] = source
+
+	return source, nil
+}
+
+// breaker returns the circuit breaker shared by the gateways at url. It
+// must be called with s.mu held.
+func (s *Sources) breaker(url string) *Breaker {
+	if s.BreakerThreshold == 0 {
+		return nil
+	}
+	if b, ok := s.breakers[url]; ok {
+		return b
+	}
+	if s.breakers == nil {
+		s.breakers = map[string]*Breaker{}
+	}
+	b := &Breaker{Threshold: s.BreakerThreshold, Cooldown: s.BreakerCooldown}
+	s.breakers[url] = b
+	return b
+}
+
+// Close closes every connection pool opened by Get.
+func (s *Sources) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for key, source := range s.open {
+		if closer, ok := source.(interface{ Close() error }); ok {
+			errs = append(errs, closer.Close())
+		}
+		delete(s.open, key)
+	}
+	return errors.Join(errs...)
+}
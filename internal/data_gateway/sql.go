@@ -0,0 +1,165 @@
+package datagateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/marcboeker/go-duckdb"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQL runs queries directly against a database/sql driver. Queries are
+// read-only at the database, in addition to the lexical guard: SQLite and
+// DuckDB databases are opened read-only and Postgres queries run in a read
+// only transaction.
+type SQL struct {
+	db         *sql.DB
+	readOnlyTx bool
+}
+
+// OpenSQL opens a connection pool for the driver. Connections are made
+// lazily, on the first query.
+func OpenSQL(driverName string, dsn string) (*SQL, error) {
+	readOnlyTx := false
+	switch driverName {
+	case "sqlite3":
+		dsn = setDSNParam(dsn, "_query_only", "true")
+	case "duckdb":
+		dsn = setDSNParam(dsn, "access_mode", "read_only")
+	default:
+		readOnlyTx = true
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	return &SQL{db: db, readOnlyTx: readOnlyTx}, nil
+}
+
+// setDSNParam sets a query parameter of a file DSN such as
+// data.db?cache=shared, replacing any value it already has.
+func setDSNParam(dsn string, key string, value string) string {
+	path, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		query = url.Values{}
+	}
+	query.Set(key, value)
+	return path + "?" + query.Encode()
+}
+
+func (s *SQL) Query(ctx context.Context, query string, params []interface{}) ([]map[string]interface{}, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if s.readOnlyTx {
+		var tx *sql.Tx
+		tx, err = s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		// nothing can be written, the transaction is only ended
+		defer tx.Rollback()
+		rows, err = tx.QueryContext(ctx, query, params...)
+	} else {
+		rows, err = s.db.QueryContext(ctx, query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columnTypes))
+		pointers := make([]interface{}, len(columnTypes))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columnTypes))
+		for i, ct := range columnTypes {
+			row[ct.Name()], err = normalizeValue(values[i], ct.DatabaseTypeName())
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", ct.Name(), err)
+			}
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
+
+// normalizeValue converts driver values into the types the HTTP gateway
+// produces: text as strings and NUMERIC/DECIMAL columns as json.Number, so
+// that their precision is kept. DuckDB's DECIMAL and HUGEINT values are
+// converted to json.Number as well.
+func normalizeValue(v interface{}, databaseType string) (interface{}, error) {
+	switch n := v.(type) {
+	case duckdb.Decimal:
+		return json.Number(decimalString(n.Value, int(n.Scale))), nil
+	case *big.Int:
+		return json.Number(n.String()), nil
+	}
+
+	b, ok := v.([]byte)
+	if !ok {
+		return v, nil
+	}
+
+	switch strings.ToUpper(databaseType) {
+	case "NUMERIC", "DECIMAL":
+		_, err := strconv.ParseFloat(string(b), 64)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(b), nil
+	default:
+		return string(b), nil
+	}
+}
+
+// decimalString formats the unscaled value of a decimal with scale
+// fractional digits.
+func decimalString(value *big.Int, scale int) string {
+	digits := new(big.Int).Abs(value).String()
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if value.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
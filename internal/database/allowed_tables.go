@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PutAllowedTables sets the tables the queries of a data product may read,
+// replacing the existing list.
+func (db *DB) PutAllowedTables(ctx context.Context, allowed *AllowedTables) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	stmt := `
+		INSERT INTO allowed_tables (data_product_id, tables)
+		VALUES ($1, $2)
+		ON CONFLICT (data_product_id) DO UPDATE SET tables = EXCLUDED.tables`
+
+	_, err := db.ExecContext(ctx, stmt, allowed.DataProductID, allowed.Tables)
+	return err
+}
+
+func (db *DB) GetAllowedTables(ctx context.Context, dataProductID uuid.UUID) (*AllowedTables, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var allowed AllowedTables
+
+	stmt := `SELECT data_product_id, tables FROM allowed_tables WHERE data_product_id = $1`
+
+	err := db.GetContext(ctx, &allowed, stmt, dataProductID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	return &allowed, true, err
+}
+
+// ListAllowedTables returns the tables the queries of a data product may
+// read, or nil if they may read any table.
+func (db *DB) ListAllowedTables(ctx context.Context, dataProductID uuid.UUID) (pq.StringArray, error) {
+	allowed, found, err := db.GetAllowedTables(ctx, dataProductID)
+	if err != nil || !found {
+		return nil, err
+	}
+	return allowed.Tables, nil
+}
+
+func (db *DB) DeleteAllowedTables(ctx context.Context, dataProductID uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `DELETE FROM allowed_tables WHERE data_product_id = $1`, dataProductID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// PutDataSource sets the data source of a data product, replacing the
+// existing one.
+func (db *DB) PutDataSource(ctx context.Context, source *DataSource) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	stmt := `
+		INSERT INTO data_sources (data_product_id, type, config)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (data_product_id) DO UPDATE SET type = EXCLUDED.type, config = EXCLUDED.config`
+
+	_, err := db.ExecContext(ctx, stmt, source.DataProductID, source.Type, string(source.Config))
+	return err
+}
+
+func (db *DB) GetDataSource(ctx context.Context, dataProductID uuid.UUID) (*DataSource, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var source DataSource
+
+	stmt := `SELECT data_product_id, type, config FROM data_sources WHERE data_product_id = $1`
+
+	err := db.GetContext(ctx, &source, stmt, dataProductID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	return &source, true, err
+}
+
+func (db *DB) DeleteDataSource(ctx context.Context, dataProductID uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `DELETE FROM data_sources WHERE data_product_id = $1`, dataProductID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -2,13 +2,80 @@ package database
 
 import (
 	"encoding/json"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/anomaly"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Query struct {
-	DataProductID uuid.UUID       `json:"data_product_id"    db:"data_product_id"`
-	Name          string          `json:"name"               db:"name"`
-	Query         string          `json:"query"              db:"query"`
-	Parameters    json.RawMessage `json:"parameters" db:"parameters"`
+	QueryID           uuid.UUID            `json:"query_id"           db:"query_id"`
+	DataProductID     uuid.UUID            `json:"data_product_id"    db:"data_product_id"`
+	Name              string               `json:"name"               db:"name"`
+	Description       string               `json:"description"        db:"description"`
+	Query             string               `json:"query"              db:"query"`
+	DefaultParameters json.RawMessage      `json:"default_parameters" db:"default_parameters"`
+	Assertions        assertion.Assertions `json:"assertions"         db:"assertions"`
+	LabelColumns      pq.StringArray       `json:"label_columns"      db:"label_columns"`
+	ValueColumns      pq.StringArray       `json:"value_columns"      db:"value_columns"`
+	Anomaly           *anomaly.Model       `json:"anomaly,omitempty"  db:"anomaly"`
+	// Template is the spec Query was generated from, if any.
+	Template *templates.Spec `json:"template,omitempty" db:"template"`
+	// Dimension and Weight place the query in the scorecard of its data
+	// product.
+	Dimension string  `json:"dimension,omitempty" db:"dimension"`
+	Weight    float64 `json:"weight"              db:"weight"`
+	// Source is the checks file the query is declared in. Such queries
+	// are synced from the file and only changed there.
+	Source string `json:"source,omitempty" db:"source"`
+	// Parameters is only set on workflow inputs and is never persisted.
+	Parameters json.RawMessage `json:"parameters,omitempty" db:"-"`
+}
+
+type QueryRun struct {
+	RunID         uuid.UUID       `json:"run_id"          db:"run_id"`
+	QueryID       uuid.NullUUID   `json:"query_id"        db:"query_id"`
+	Name          string          `json:"name"            db:"name"`
+	DataProductID uuid.UUID       `json:"data_product_id" db:"data_product_id"`
+	Parameters    json.RawMessage `json:"parameters"      db:"parameters"`
+	SQLHash       string          `json:"sql_hash"        db:"sql_hash"`
+	Value         *float64        `json:"value"           db:"value"`
+	Series        json.RawMessage `json:"series"          db:"series"`
+	Status        string          `json:"status"          db:"status"`
+	DurationMS    int64           `json:"duration_ms"     db:"duration_ms"`
+	Error         string          `json:"error"           db:"error"`
+	WorkflowID    string          `json:"workflow_id"     db:"workflow_id"`
+	StartedAt     time.Time       `json:"started_at"      db:"started_at"`
+}
+
+type RunValue struct {
+	Value     float64   `db:"value"`
+	StartedAt time.Time `db:"started_at"`
+}
+
+// NotificationChannel is a sink for alerts about the checks of one query or
+// of every query of a data product.
+type NotificationChannel struct {
+	ChannelID     uuid.UUID       `json:"channel_id"      db:"channel_id"`
+	DataProductID uuid.NullUUID   `json:"data_product_id" db:"data_product_id"`
+	QueryID       uuid.NullUUID   `json:"query_id"        db:"query_id"`
+	Type          string          `json:"type"            db:"type"`
+	Config        json.RawMessage `json:"config"          db:"config"`
+	MinStatus     string          `json:"min_status"      db:"min_status"`
+}
+
+// DataSource is where the queries of a data product are executed.
+type DataSource struct {
+	DataProductID uuid.UUID       `json:"data_product_id" db:"data_product_id"`
+	Type          string          `json:"type"            db:"type"`
+	Config        json.RawMessage `json:"config"          db:"config"`
+}
+
+// AllowedTables limits the tables the queries of a data product may read.
+type AllowedTables struct {
+	DataProductID uuid.UUID      `json:"data_product_id" db:"data_product_id"`
+	Tables        pq.StringArray `json:"tables"          db:"tables"`
 }
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/huandu/go-sqlbuilder"
+)
+
+const notificationChannelColumns = `channel_id, data_product_id, query_id, type, config, min_status`
+
+func (db *DB) InsertNotificationChannel(ctx context.Context, channel *NotificationChannel) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	channel.ChannelID = uuid.New()
+
+	stmt := `
+		INSERT INTO notification_channels (channel_id, data_product_id, query_id, type, config, min_status)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := db.ExecContext(ctx, stmt,
+		channel.ChannelID,
+		channel.DataProductID,
+		channel.QueryID,
+		channel.Type,
+		string(channel.Config),
+		channel.MinStatus,
+	)
+	return err
+}
+
+func (db *DB) GetNotificationChannel(ctx context.Context, id uuid.UUID) (*NotificationChannel, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var channel NotificationChannel
+
+	stmt := `SELECT ` + notificationChannelColumns + ` FROM notification_channels WHERE channel_id = $1`
+
+	err := db.GetContext(ctx, &channel, stmt, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	return &channel, true, err
+}
+
+// ListNotificationChannels returns the channels configured for the query
+// or data product; a nil ID matches any.
+func (db *DB) ListNotificationChannels(ctx context.Context, dataProductID uuid.UUID, queryID uuid.UUID) ([]NotificationChannel, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select(notificationChannelColumns).From("notification_channels").OrderBy("type")
+	if dataProductID != uuid.Nil {
+		sb.Where(sb.Equal("data_product_id", dataProductID))
+	}
+	if queryID != uuid.Nil {
+		sb.Where(sb.Equal("query_id", queryID))
+	}
+	stmt, args := sb.Build()
+
+	channels := []NotificationChannel{}
+	err := db.SelectContext(ctx, &channels, stmt, args...)
+	return channels, err
+}
+
+// ListChannelsForQuery returns the channels that receive alerts for a
+// query: those configured for the query itself and those configured for
+// its whole data product.
+func (db *DB) ListChannelsForQuery(ctx context.Context, queryID uuid.UUID, dataProductID uuid.UUID) ([]NotificationChannel, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	stmt := `SELECT ` + notificationChannelColumns + ` FROM notification_channels
+		WHERE query_id = $1 OR (query_id IS NULL AND data_product_id = $2)`
+
+	channels := []NotificationChannel{}
+	err := db.SelectContext(ctx, &channels, stmt, queryID, dataProductID)
+	return channels, err
+}
+
+func (db *DB) DeleteNotificationChannel(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `DELETE FROM notification_channels WHERE channel_id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/lib/pq"
+)
+
+const queryColumns = `query_id, data_product_id, name, COALESCE(description, '') AS description, query,
+	COALESCE(default_parameters, '{}'::jsonb) AS default_parameters, assertions,
+	label_columns, value_columns, anomaly, template, COALESCE(dimension, '') AS dimension, weight,
+	COALESCE(source, '') AS source`
+
+func (db *DB) InsertQuery(ctx context.Context, query *Query) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query.QueryID = uuid.New()
+
+	stmt := `
+		INSERT INTO queries (query_id, data_product_id, name, description, query, default_parameters, assertions,
+			label_columns, value_columns, anomaly, template, dimension, weight, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, NULLIF($14, ''))`
+
+	_, err := db.ExecContext(ctx, stmt,
+		query.QueryID,
+		query.DataProductID,
+		query.Name,
+		query.Description,
+		query.Query,
+		string(query.DefaultParameters),
+		query.Assertions,
+		stringArray(query.LabelColumns),
+		stringArray(query.ValueColumns),
+		query.Anomaly,
+		query.Template,
+		query.Dimension,
+		query.Weight,
+		query.Source,
+	)
+	return err
+}
+
+func (db *DB) GetQuery(ctx context.Context, id uuid.UUID) (*Query, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var query Query
+
+	stmt := `SELECT ` + queryColumns + ` FROM queries WHERE query_id = $1`
+
+	err := db.GetContext(ctx, &query, stmt, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	return &query, true, err
+}
+
+// ListQueries returns every stored query, or only those belonging to
+// dataProductID when it is not uuid.Nil.
+func (db *DB) ListQueries(ctx context.Context, dataProductID uuid.UUID) ([]Query, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	queries := []Query{}
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select(queryColumns).From("queries").OrderBy("name")
+	if dataProductID != uuid.Nil {
+		sb.Where(sb.Equal("data_product_id", dataProductID))
+	}
+	stmt, args := sb.Build()
+
+	err := db.SelectContext(ctx, &queries, stmt, args...)
+	return queries, err
+}
+
+func (db *DB) UpdateQuery(ctx context.Context, query *Query) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	stmt := `
+		UPDATE queries
+		SET data_product_id = $2, name = $3, description = $4, query = $5, default_parameters = $6,
+			assertions = $7, label_columns = $8, value_columns = $9,
+			anomaly = $10, template = $11, dimension = NULLIF($12, ''), weight = $13,
+			source = NULLIF($14, '')
+		WHERE query_id = $1`
+
+	result, err := db.ExecContext(ctx, stmt,
+		query.QueryID,
+		query.DataProductID,
+		query.Name,
+		query.Description,
+		query.Query,
+		string(query.DefaultParameters),
+		query.Assertions,
+		stringArray(query.LabelColumns),
+		stringArray(query.ValueColumns),
+		query.Anomaly,
+		query.Template,
+		query.Dimension,
+		query.Weight,
+		query.Source,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (db *DB) DeleteQuery(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `DELETE FROM queries WHERE query_id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// stringArray stores a nil array as an empty one to satisfy NOT NULL
+// array columns.
+func stringArray(a pq.StringArray) pq.StringArray {
+	if a == nil {
+		return pq.StringArray{}
+	}
+	return a
+}
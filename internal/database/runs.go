@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huandu/go-sqlbuilder"
+)
+
+const queryRunColumns = `run_id, query_id, name, data_product_id, COALESCE(parameters, '{}'::jsonb) AS parameters,
+	sql_hash, value, COALESCE(series, 'null'::jsonb) AS series, COALESCE(status, '') AS status, duration_ms, COALESCE(error, '') AS error,
+	COALESCE(workflow_id, '') AS workflow_id, started_at`
+
+func (db *DB) InsertQueryRun(ctx context.Context, run *QueryRun) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	run.RunID = uuid.New()
+
+	stmt := `
+		INSERT INTO query_runs (run_id, query_id, name, data_product_id, parameters, sql_hash, value, status,
+			duration_ms, error, workflow_id, started_at, series)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), NULLIF($11, ''), $12, $13)`
+
+	parameters := string(run.Parameters)
+	if parameters == "" {
+		parameters = "{}"
+	}
+
+	var series *string
+	if run.Series != nil {
+		s := string(run.Series)
+		series = &s
+	}
+
+	_, err := db.ExecContext(ctx, stmt,
+		run.RunID,
+		run.QueryID,
+		run.Name,
+		run.DataProductID,
+		parameters,
+		run.SQLHash,
+		run.Value,
+		run.Status,
+		run.DurationMS,
+		run.Error,
+		run.WorkflowID,
+		run.StartedAt,
+		series,
+	)
+	return err
+}
+
+type QueryRunFilter struct {
+	QueryID  uuid.UUID
+	From     time.Time
+	To       time.Time
+	Page     int
+	PageSize int
+}
+
+// ListQueryRuns returns the runs of a query, newest first, together with
+// the total number of runs matching the filter. Zero From and To values
+// leave the time range open.
+func (db *DB) ListQueryRuns(ctx context.Context, filter QueryRunFilter) ([]QueryRun, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	where := func(sb *sqlbuilder.SelectBuilder) {
+		sb.Where(sb.Equal("query_id", filter.QueryID))
+		if !filter.From.IsZero() {
+			sb.Where(sb.GreaterEqualThan("started_at", filter.From))
+		}
+		if !filter.To.IsZero() {
+			sb.Where(sb.LessThan("started_at", filter.To))
+		}
+	}
+
+	cb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	cb.Select("count(*)").From("query_runs")
+	where(cb)
+	stmt, args := cb.Build()
+
+	var total int
+	err := db.GetContext(ctx, &total, stmt, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select(queryRunColumns).From("query_runs")
+	where(sb)
+	sb.OrderBy("started_at").Desc()
+	sb.Limit(filter.PageSize).Offset((filter.Page - 1) * filter.PageSize)
+	stmt, args = sb.Build()
+
+	runs := []QueryRun{}
+	err = db.SelectContext(ctx, &runs, stmt, args...)
+	return runs, total, err
+}
+
+// GetPreviousRunValue returns the value of the latest successful run of the
+// query identified by queryID, or by data product and name for ad-hoc runs.
+func (db *DB) GetPreviousRunValue(ctx context.Context, queryID uuid.UUID, dataProductID uuid.UUID, name string) (*float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select("value").From("query_runs")
+	if queryID != uuid.Nil {
+		sb.Where(sb.Equal("query_id", queryID))
+	} else {
+		sb.Where(sb.IsNull("query_id"), sb.Equal("data_product_id", dataProductID), sb.Equal("name", name))
+	}
+	sb.Where(sb.IsNotNull("value"), sb.IsNull("error"))
+	sb.OrderBy("started_at").Desc().Limit(1)
+	stmt, args := sb.Build()
+
+	var values []float64
+	err := db.SelectContext(ctx, &values, stmt, args...)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return &values[0], nil
+}
+
+// ListRunValues returns the values of successful runs of a stored query,
+// newest first. At most limit runs started at or after since are returned;
+// a zero since leaves the range open.
+func (db *DB) ListRunValues(ctx context.Context, queryID uuid.UUID, since time.Time, limit int) ([]RunValue, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	sb := sqlbuilder.PostgreSQL.NewSelectBuilder()
+	sb.Select("value", "started_at").From("query_runs")
+	sb.Where(sb.Equal("query_id", queryID), sb.IsNotNull("value"), sb.IsNull("error"))
+	if !since.IsZero() {
+		sb.Where(sb.GreaterEqualThan("started_at", since))
+	}
+	sb.OrderBy("started_at").Desc().Limit(limit)
+	stmt, args := sb.Build()
+
+	values := []RunValue{}
+	err := db.SelectContext(ctx, &values, stmt, args...)
+	return values, err
+}
+
+// GetLatestRun returns the most recent run of a stored query that is not
+// an attempt of the workflow execution workflowID, empty for runs outside
+// of workflows.
+func (db *DB) GetLatestRun(ctx context.Context, queryID uuid.UUID, workflowID string) (*QueryRun, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var run QueryRun
+
+	stmt := `SELECT ` + queryRunColumns + ` FROM query_runs
+		WHERE query_id = $1 AND ($2 = '' OR workflow_id IS DISTINCT FROM $2)
+		ORDER BY started_at DESC LIMIT 1`
+
+	err := db.GetContext(ctx, &run, stmt, queryID, workflowID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	return &run, true, err
+}
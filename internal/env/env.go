@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -82,3 +83,21 @@ func GetBool(key string, defaultValue bool) bool {
 	}
 	return boolValue
 }
+
+func GetDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		if strVal, ok := config[key].(string); ok {
+			durationValue, err := time.ParseDuration(strVal)
+			if err == nil {
+				return durationValue
+			}
+		}
+		return defaultValue
+	}
+	durationValue, err := time.ParseDuration(value)
+	if err != nil {
+		panic(err)
+	}
+	return durationValue
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// QueryRowOutput holds one series per row and value column of queries that
+// map result columns to labels. Label names differ from query to query, so
+// it is an unchecked collector that builds const metrics on every scrape.
+var QueryRowOutput = &labeledGauge{
+	name:   "query_row_output",
+	help:   "Sets the result for every row and value column of labeled queries.",
+	series: map[string][]LabeledValue{},
+}
+
+// ReservedLabels are set on every QueryRowOutput series and cannot be used
+// as label columns.
+var ReservedLabels = []string{"name", "data_product_id", "column"}
+
+var labelNameRX = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidLabelName reports whether name can be used as a label column.
+func ValidLabelName(name string) bool {
+	if !labelNameRX.MatchString(name) || len(name) >= 2 && name[:2] == "__" {
+		return false
+	}
+	for _, reserved := range ReservedLabels {
+		if name == reserved {
+			return false
+		}
+	}
+	return true
+}
+
+type LabeledValue struct {
+	Column string
+	Labels map[string]string
+	Value  float64
+}
+
+type labeledGauge struct {
+	name string
+	help string
+
+	mu     sync.Mutex
+	series map[string][]LabeledValue
+}
+
+func (g *labeledGauge) Describe(ch chan<- *prometheus.Desc) {}
+
+func (g *labeledGauge) Collect(ch chan<- prometheus.Metric) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	keys := make([]string, 0, len(g.series))
+	for key := range g.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, v := range g.series[key] {
+			names := make([]string, 0, len(v.Labels))
+			for name := range v.Labels {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			values := make([]string, 0, len(names))
+			for _, name := range names {
+				values = append(values, v.Labels[name])
+			}
+
+			desc := prometheus.NewDesc(g.name, g.help, names, nil)
+			m, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, v.Value, values...)
+			if err != nil {
+				ch <- prometheus.NewInvalidMetric(desc, err)
+				continue
+			}
+			ch <- m
+		}
+	}
+}
+
+// SetLabeledValues replaces every series of the named query, so rows that no
+// longer appear in the result stop being exported.
+func SetLabeledValues(name string, values []LabeledValue, data_product_id string) {
+	series := make([]LabeledValue, 0, len(values))
+	for _, v := range values {
+		labels := make(map[string]string, len(v.Labels)+len(ReservedLabels))
+		for label, value := range v.Labels {
+			labels[label] = value
+		}
+		labels["name"] = name
+		labels["data_product_id"] = data_product_id
+		labels["column"] = v.Column
+
+		series = append(series, LabeledValue{Column: v.Column, Labels: labels, Value: v.Value})
+	}
+
+	QueryRowOutput.mu.Lock()
+	defer QueryRowOutput.mu.Unlock()
+	QueryRowOutput.series[data_product_id+"/"+name] = series
+}
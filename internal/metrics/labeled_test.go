@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetLabeledValues(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics.QueryRowOutput)
+
+	metrics.SetLabeledValues("nulls_by_source", []metrics.LabeledValue{
+		{Column: "null_count", Labels: map[string]string{"source_system": "emr"}, Value: 3},
+		{Column: "null_count", Labels: map[string]string{"source_system": "claims"}, Value: 0},
+	}, "dp1")
+	metrics.SetLabeledValues("rows_by_region", []metrics.LabeledValue{
+		{Column: "row_count", Labels: map[string]string{"region": "us"}, Value: 10},
+	}, "dp1")
+
+	expected := `
+# HELP query_row_output Sets the result for every row and value column of labeled queries.
+# TYPE query_row_output gauge
+query_row_output{column="null_count",data_product_id="dp1",name="nulls_by_source",source_system="claims"} 0
+query_row_output{column="null_count",data_product_id="dp1",name="nulls_by_source",source_system="emr"} 3
+query_row_output{column="row_count",data_product_id="dp1",name="rows_by_region",region="us"} 10
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "query_row_output"); err != nil {
+		t.Error(err)
+	}
+
+	// rows missing from the latest result are no longer exported
+	metrics.SetLabeledValues("nulls_by_source", []metrics.LabeledValue{
+		{Column: "null_count", Labels: map[string]string{"source_system": "emr"}, Value: 1},
+	}, "dp1")
+
+	if n := testutil.CollectAndCount(metrics.QueryRowOutput); n != 2 {
+		t.Errorf("expected 2 series, got %d", n)
+	}
+}
+
+func TestValidLabelName(t *testing.T) {
+	tests := map[string]bool{
+		"source_system": true,
+		"_region":       true,
+		"1st":           false,
+		"with-dash":     false,
+		"__internal":    false,
+		"name":          false,
+		"column":        false,
+	}
+
+	for name, want := range tests {
+		if got := metrics.ValidLabelName(name); got != want {
+			t.Errorf("ValidLabelName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -12,6 +12,73 @@ var QueryOutput = prometheus.NewGaugeVec(
 	[]string{"name", "data_product_id"},
 )
 
+var QueryStatus = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "query_status",
+		Help: "Sets the assertion status for every query: 0 = PASS, 1 = WARN, 2 = FAIL.",
+	},
+	[]string{"name", "data_product_id"},
+)
+
+var QueryExpectedValue = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "query_expected_value",
+		Help: "Sets the expected value and band of queries with an anomaly model.",
+	},
+	[]string{"name", "data_product_id", "bound"},
+)
+
+var QueryAnomaly = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "query_anomaly",
+		Help: "Sets 1 when the latest result of a query deviates from its expected band, 0 otherwise.",
+	},
+	[]string{"name", "data_product_id"},
+)
+
+var DataProductQualityScore = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "data_product_quality_score",
+		Help: "Sets the health score, between 0 and 100, of data products overall and per quality dimension.",
+	},
+	[]string{"data_product_id", "dimension"},
+)
+
+// OverallDimension is the dimension label of the overall score of a data
+// product.
+const OverallDimension = "overall"
+
 func SetMetricValue(name string, value float64, data_product_id string) {
 	QueryOutput.With(prometheus.Labels{"name": name, "data_product_id": data_product_id}).Set(value)
 }
+
+// ClearMetricValue removes the result of a query that returned no value, so
+// that neither zero nor its previous result is reported for it.
+func ClearMetricValue(name string, data_product_id string) {
+	QueryOutput.Delete(prometheus.Labels{"name": name, "data_product_id": data_product_id})
+}
+
+func SetStatusValue(name string, status float64, data_product_id string) {
+	QueryStatus.With(prometheus.Labels{"name": name, "data_product_id": data_product_id}).Set(status)
+}
+
+func SetExpectedBand(name string, lower float64, expected float64, upper float64, anomalous bool, data_product_id string) {
+	QueryExpectedValue.With(prometheus.Labels{"name": name, "data_product_id": data_product_id, "bound": "lower"}).Set(lower)
+	QueryExpectedValue.With(prometheus.Labels{"name": name, "data_product_id": data_product_id, "bound": "expected"}).Set(expected)
+	QueryExpectedValue.With(prometheus.Labels{"name": name, "data_product_id": data_product_id, "bound": "upper"}).Set(upper)
+
+	value := 0.0
+	if anomalous {
+		value = 1
+	}
+	QueryAnomaly.With(prometheus.Labels{"name": name, "data_product_id": data_product_id}).Set(value)
+}
+
+// SetQualityScores replaces the scores of a data product, so dimensions
+// without a score are no longer reported.
+func SetQualityScores(scores map[string]float64, data_product_id string) {
+	DataProductQualityScore.DeletePartialMatch(prometheus.Labels{"data_product_id": data_product_id})
+	for dimension, score := range scores {
+		DataProductQualityScore.With(prometheus.Labels{"data_product_id": data_product_id, "dimension": dimension}).Set(score)
+	}
+}
@@ -0,0 +1,32 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetQualityScores(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics.DataProductQualityScore)
+
+	metrics.SetQualityScores(map[string]float64{"overall": 80, "completeness": 100, "freshness": 50}, "dp1")
+	metrics.SetQualityScores(map[string]float64{"overall": 100}, "dp2")
+
+	// dimensions without a score in the latest suite run are dropped
+	metrics.SetQualityScores(map[string]float64{"overall": 90, "completeness": 90}, "dp1")
+
+	expected := `
+# HELP data_product_quality_score Sets the health score, between 0 and 100, of data products overall and per quality dimension.
+# TYPE data_product_quality_score gauge
+data_product_quality_score{data_product_id="dp1",dimension="completeness"} 90
+data_product_quality_score{data_product_id="dp1",dimension="overall"} 90
+data_product_quality_score{data_product_id="dp2",dimension="overall"} 100
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "data_product_quality_score"); err != nil {
+		t.Error(err)
+	}
+}
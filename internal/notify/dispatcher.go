@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+)
+
+// Dispatcher sends an event to every channel configured for the query or
+// its data product. A channel is only notified when the check crosses its
+// minimum status or changes status above it, so a check that keeps failing
+// does not alert on every run, and once more when it recovers.
+type Dispatcher struct {
+	DB     *database.DB
+	SMTP   SMTPConfig
+	Logger *slog.Logger
+	Client *http.Client
+}
+
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
+	channels, err := d.DB.ListChannelsForQuery(ctx, event.QueryID, event.DataProductID)
+	if err != nil {
+		d.Logger.Error("could not fetch notification channels", slog.Any("name", event.Name), slog.Any("err", err))
+		return
+	}
+
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	for _, channel := range channels {
+		send, resolved := Transition(event.PreviousStatus, event.Status, assertion.Status(channel.MinStatus))
+		if !send {
+			continue
+		}
+
+		notifier, err := New(channel.Type, channel.Config, d.SMTP, client)
+		if err != nil {
+			d.Logger.Error("invalid notification channel", slog.Any("channel_id", channel.ChannelID), slog.Any("err", err))
+			continue
+		}
+
+		channelEvent := event
+		channelEvent.Resolved = resolved
+
+		ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+		err = notifier.Notify(ctx, channelEvent)
+		cancel()
+		if err != nil {
+			d.Logger.Error("could not send notification", slog.Any("channel_id", channel.ChannelID), slog.Any("type", channel.Type), slog.Any("err", err))
+		}
+	}
+}
+
+// Transition reports whether a channel with minimum status min is notified
+// when a check goes from previous to current, and whether that notification
+// resolves an earlier alert. previous is empty for the first run.
+func Transition(previous, current, min assertion.Status) (send bool, resolved bool) {
+	if min == "" {
+		min = assertion.StatusFail
+	}
+	alerting := current.Code() >= min.Code()
+	wasAlerting := previous != "" && previous.Code() >= min.Code()
+
+	switch {
+	case alerting && (!wasAlerting || current != previous):
+		return true, false
+	case !alerting && wasAlerting:
+		return true, true
+	default:
+		return false, false
+	}
+}
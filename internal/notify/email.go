@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Email sends a plain text message through the configured SMTP server.
+type Email struct {
+	To []string `json:"to"`
+
+	smtp SMTPConfig
+}
+
+func (n *Email) validate() error {
+	if len(n.To) == 0 {
+		return errors.New("email channel requires at least one recipient in to")
+	}
+	// recipients are sent as is in the To header and RCPT commands, so only
+	// plain addresses are accepted
+	for _, to := range n.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil || addr.Address != to {
+			return fmt.Errorf("invalid recipient %q, want a plain address such as alerts@example.com", to)
+		}
+	}
+	if n.smtp.Host == "" || n.smtp.From == "" {
+		return errors.New("email channels require SMTP_HOST and SMTP_FROM to be configured")
+	}
+	return nil
+}
+
+func (n *Email) Notify(ctx context.Context, event Event) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Check: %s\r\n", event.Name)
+	fmt.Fprintf(&body, "Data product: %s\r\n", event.DataProductID)
+	fmt.Fprintf(&body, "Status: %s\r\n", event.Status)
+	if event.PreviousStatus != "" {
+		fmt.Fprintf(&body, "Previous status: %s\r\n", event.PreviousStatus)
+	}
+	if event.Value != nil {
+		fmt.Fprintf(&body, "Value: %s\r\n", strconv.FormatFloat(*event.Value, 'f', -1, 64))
+	}
+	if event.Message != "" {
+		fmt.Fprintf(&body, "Message: %s\r\n", event.Message)
+	}
+	fmt.Fprintf(&body, "Run: %s\r\nTime: %s\r\n", event.RunID, event.Time.Format("2006-01-02 15:04:05 MST"))
+
+	// error messages may span lines, keep them out of the headers
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(event.Summary())
+
+	msg := "From: " + n.smtp.From + "\r\n" +
+		"To: " + strings.Join(n.To, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body.String()
+
+	return n.send(ctx, []byte(msg))
+}
+
+// send delivers msg the way smtp.SendMail does, but gives up on servers
+// that stop responding once ctx is done, or after defaultTimeout.
+func (n *Email) send(ctx context.Context, msg []byte) error {
+	addr := net.JoinHostPort(n.smtp.Host, strconv.Itoa(n.smtp.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, n.smtp.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: n.smtp.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if n.smtp.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(n.smtp.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		err = c.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
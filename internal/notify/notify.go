@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TypeWebhook   = "webhook"
+	TypeSlack     = "slack"
+	TypeEmail     = "email"
+	TypePagerDuty = "pagerduty"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Event describes a change in the status of a check. Resolved events are
+// sent when a check recovers after a notification was sent for it.
+type Event struct {
+	QueryID        uuid.UUID        `json:"query_id"`
+	RunID          uuid.UUID        `json:"run_id"`
+	Name           string           `json:"name"`
+	DataProductID  uuid.UUID        `json:"data_product_id"`
+	Status         assertion.Status `json:"status"`
+	PreviousStatus assertion.Status `json:"previous_status,omitempty"`
+	Value          *float64         `json:"value,omitempty"`
+	Message        string           `json:"message,omitempty"`
+	Resolved       bool             `json:"resolved"`
+	Time           time.Time        `json:"time"`
+}
+
+func (e Event) Summary() string {
+	if e.Resolved {
+		return fmt.Sprintf("[RESOLVED] %s is %s again", e.Name, e.Status)
+	}
+	summary := fmt.Sprintf("[%s] %s", e.Status, e.Name)
+	if e.Message != "" {
+		summary += ": " + e.Message
+	}
+	return summary
+}
+
+// DedupKey identifies the alert for a check so that sinks which track
+// incidents can correlate trigger and resolve messages.
+func (e Event) DedupKey() string {
+	return "data-quality-" + e.QueryID.String()
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// SMTPConfig holds the server settings shared by every email channel.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// New builds the notifier for a channel of the given type from its JSON
+// configuration.
+func New(channelType string, config json.RawMessage, smtp SMTPConfig, client *http.Client) (Notifier, error) {
+	switch channelType {
+	case TypeWebhook:
+		n := &Webhook{client: client}
+		return n, decodeConfig(config, n, n.validate)
+	case TypeSlack:
+		n := &Slack{client: client}
+		return n, decodeConfig(config, n, n.validate)
+	case TypeEmail:
+		n := &Email{smtp: smtp}
+		return n, decodeConfig(config, n, n.validate)
+	case TypePagerDuty:
+		n := &PagerDuty{client: client}
+		return n, decodeConfig(config, n, n.validate)
+	default:
+		return nil, fmt.Errorf("unsupported notification channel type %q", channelType)
+	}
+}
+
+func decodeConfig(config json.RawMessage, dst interface{}, validate func() error) error {
+	err := json.Unmarshal(config, dst)
+	if err != nil {
+		return fmt.Errorf("invalid channel config: %w", err)
+	}
+	return validate()
+}
+
+// redacted replaces secrets in configs returned by Redact.
+const redacted = "xxxxx"
+
+// Redact returns the config of a channel with its secrets replaced, so that
+// it can be shown to users who may see the channel but not post to it: the
+// Slack webhook path, the PagerDuty routing key, webhook headers and any
+// password or query parameters in URLs. A config that cannot be decoded
+// is redacted completely.
+func Redact(channelType string, config json.RawMessage) json.RawMessage {
+	var fields map[string]interface{}
+	err := json.Unmarshal(config, &fields)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+
+	if s, ok := fields["url"].(string); ok {
+		fields["url"] = redactURL(s, channelType == TypeSlack)
+	}
+	if _, ok := fields["routing_key"]; ok {
+		fields["routing_key"] = redacted
+	}
+	if headers, ok := fields["headers"].(map[string]interface{}); ok {
+		for name := range headers {
+			headers[name] = redacted
+		}
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+	return b
+}
+
+// redactURL redacts the password and query parameters of rawURL and, with
+// path, its path, which holds the secret of Slack webhook URLs.
+func redactURL(rawURL string, path bool) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if path && u.Path != "" {
+		u.Path = "/" + redacted
+		u.RawPath = ""
+	}
+	query := u.Query()
+	for key := range query {
+		query.Set(key, redacted)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
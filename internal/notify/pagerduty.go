@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty sends Events API v2 trigger and resolve events. The query ID is
+// used as dedup key, so a recovering check resolves its own incident.
+type PagerDuty struct {
+	RoutingKey string `json:"routing_key"`
+	URL        string `json:"url"`
+
+	client *http.Client
+}
+
+type pagerDutyPayload struct {
+	Summary       string `json:"summary"`
+	Source        string `json:"source"`
+	Severity      string `json:"severity"`
+	Timestamp     string `json:"timestamp"`
+	Group         string `json:"group"`
+	CustomDetails Event  `json:"custom_details"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+func (n *PagerDuty) validate() error {
+	if n.RoutingKey == "" {
+		return errors.New("pagerduty channel requires routing_key")
+	}
+	if n.URL == "" {
+		n.URL = pagerDutyEventsURL
+	}
+	return nil
+}
+
+func (n *PagerDuty) Notify(ctx context.Context, event Event) error {
+	pdEvent := pagerDutyEvent{
+		RoutingKey:  n.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    event.DedupKey(),
+	}
+
+	if event.Resolved {
+		pdEvent.EventAction = "resolve"
+	} else {
+		severity := "critical"
+		if event.Status == assertion.StatusWarn {
+			severity = "warning"
+		}
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:       event.Summary(),
+			Source:        "data-quality-metrics-framework",
+			Severity:      severity,
+			Timestamp:     event.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+			Group:         event.DataProductID.String(),
+			CustomDetails: event,
+		}
+	}
+
+	return postJSON(ctx, n.client, n.URL, nil, pdEvent)
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+)
+
+// Slack posts a message to a Slack-compatible incoming webhook.
+type Slack struct {
+	URL string `json:"url"`
+
+	client *http.Client
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+func (n *Slack) validate() error {
+	if n.URL == "" {
+		return errors.New("slack channel requires url")
+	}
+	return nil
+}
+
+func (n *Slack) Notify(ctx context.Context, event Event) error {
+	color := "danger"
+	switch {
+	case event.Resolved:
+		color = "good"
+	case event.Status == assertion.StatusWarn:
+		color = "warning"
+	}
+
+	fields := []slackField{
+		{Title: "Status", Value: string(event.Status), Short: true},
+		{Title: "Data product", Value: event.DataProductID.String(), Short: true},
+	}
+	if event.Value != nil {
+		fields = append(fields, slackField{Title: "Value", Value: strconv.FormatFloat(*event.Value, 'f', -1, 64), Short: true})
+	}
+	fields = append(fields, slackField{Title: "Run", Value: fmt.Sprint(event.RunID), Short: false})
+
+	msg := slackMessage{
+		Text:        event.Summary(),
+		Attachments: []slackAttachment{{Color: color, Fields: fields}},
+	}
+	return postJSON(ctx, n.client, n.URL, nil, msg)
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Webhook posts the event as JSON to an arbitrary URL.
+type Webhook struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+
+	client *http.Client
+}
+
+func (n *Webhook) validate() error {
+	if n.URL == "" {
+		return errors.New("webhook channel requires url")
+	}
+	return nil
+}
+
+func (n *Webhook) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, n.client, n.URL, n.Headers, event)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshaling payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making POST request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("got status code: %v from %v : %v", resp.StatusCode, url, string(body))
+	}
+	return nil
+}
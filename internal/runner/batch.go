@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+)
+
+// Batch runs many queries with a bounded number of workers. Failing items
+// do not fail the batch; with FailFast the first item that errors or whose
+// assertions fail stops the batch and the items not yet started are
+// skipped.
+type Batch struct {
+	Workers  int
+	FailFast bool
+}
+
+// BatchResult is the outcome of one item of a batch, identified by its
+// index in the batch.
+type BatchResult struct {
+	Index   int     `json:"index"`
+	Result  *Result `json:"result,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	Skipped bool    `json:"skipped,omitempty"`
+}
+
+// BatchSummary counts the items of a batch by outcome. Items without
+// assertions count as passed.
+type BatchSummary struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Warned  int `json:"warned"`
+	Failed  int `json:"failed"`
+	Errored int `json:"errored"`
+	Skipped int `json:"skipped"`
+}
+
+// Run calls run for the items 0 to n-1 and returns their results in
+// order. Items are started in order; items that were not started because
+// the batch stopped or ctx was done are reported as skipped.
+func (b Batch) Run(ctx context.Context, n int, run func(ctx context.Context, i int) (*Result, error)) []BatchResult {
+	results := make([]BatchResult, n)
+	for i := range results {
+		results[i] = BatchResult{Index: i, Skipped: true}
+	}
+
+	workers := b.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		stopped atomic.Bool
+		wg      sync.WaitGroup
+		indexes = make(chan int)
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// an item handed out just before another one failed
+				if stopped.Load() || ctx.Err() != nil {
+					continue
+				}
+
+				result, err := run(ctx, i)
+				results[i].Skipped = false
+				results[i].Result = result
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+				if b.FailFast && (err != nil || (result != nil && result.Status == assertion.StatusFail)) {
+					stopped.Store(true)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		if stopped.Load() {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// Summarize counts results by outcome.
+func Summarize(results []BatchResult) BatchSummary {
+	summary := BatchSummary{Total: len(results)}
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			summary.Skipped++
+		case r.Error != "":
+			summary.Errored++
+		case r.Result != nil && r.Result.Status == assertion.StatusFail:
+			summary.Failed++
+		case r.Result != nil && r.Result.Status == assertion.StatusWarn:
+			summary.Warned++
+		default:
+			summary.Passed++
+		}
+	}
+	return summary
+}
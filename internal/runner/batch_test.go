@@ -0,0 +1,117 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
+)
+
+func TestBatch(t *testing.T) {
+	// items fail at index 2 with an error and at index 4 with a failed
+	// assertion, every other item passes
+	runItem := func(ctx context.Context, i int) (*runner.Result, error) {
+		switch i {
+		case 2:
+			return nil, errors.New("gateway down")
+		case 4:
+			return &runner.Result{Status: assertion.StatusFail}, nil
+		case 5:
+			return &runner.Result{Status: assertion.StatusWarn}, nil
+		}
+		return &runner.Result{Status: assertion.StatusPass}, nil
+	}
+
+	tests := []struct {
+		name        string
+		batch       runner.Batch
+		n           int
+		wantSummary runner.BatchSummary
+	}{
+		{
+			name:        "empty",
+			batch:       runner.Batch{Workers: 4},
+			wantSummary: runner.BatchSummary{},
+		},
+		{
+			name:        "failures do not stop the batch",
+			batch:       runner.Batch{Workers: 3},
+			n:           8,
+			wantSummary: runner.BatchSummary{Total: 8, Passed: 5, Warned: 1, Failed: 1, Errored: 1},
+		},
+		{
+			name:        "fail fast on error",
+			batch:       runner.Batch{Workers: 1, FailFast: true},
+			n:           8,
+			wantSummary: runner.BatchSummary{Total: 8, Passed: 2, Errored: 1, Skipped: 5},
+		},
+		{
+			name:        "fail fast without failures",
+			batch:       runner.Batch{Workers: 2, FailFast: true},
+			n:           2,
+			wantSummary: runner.BatchSummary{Total: 2, Passed: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := tt.batch.Run(context.Background(), tt.n, runItem)
+			if len(results) != tt.n {
+				t.Fatalf("got %d results, want %d", len(results), tt.n)
+			}
+			for i, r := range results {
+				if r.Index != i {
+					t.Errorf("result %d has index %d", i, r.Index)
+				}
+			}
+			if got := runner.Summarize(results); got != tt.wantSummary {
+				t.Errorf("got summary %+v, want %+v", got, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestBatchWorkers(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	batch := runner.Batch{Workers: 3}
+
+	results := batch.Run(context.Background(), 12, func(ctx context.Context, i int) (*runner.Result, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return &runner.Result{}, nil
+	})
+
+	if got := runner.Summarize(results).Passed; got != 12 {
+		t.Errorf("got %d passed items, want 12", got)
+	}
+	if got := maxRunning.Load(); got > 3 {
+		t.Errorf("ran %d items at once, want at most 3", got)
+	}
+}
+
+func TestBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	batch := runner.Batch{Workers: 1}
+
+	results := batch.Run(ctx, 5, func(ctx context.Context, i int) (*runner.Result, error) {
+		if i == 1 {
+			cancel()
+		}
+		return &runner.Result{}, nil
+	})
+
+	summary := runner.Summarize(results)
+	if summary.Passed != 2 || summary.Skipped != 3 {
+		t.Errorf("got summary %+v, want 2 passed and 3 skipped items", summary)
+	}
+}
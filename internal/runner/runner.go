@@ -0,0 +1,497 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/anomaly"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+	"xcaliber/data-quality-metrics-framework/internal/notify"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+)
+
+// ErrNotSingleValue is reported when a query that must produce a metric
+// does not return exactly one row with one column.
+var ErrNotSingleValue = errors.New("query does not return a single value, returns multiple rows or columns")
+
+var errNoValue = errors.New("query returns NULL")
+
+// Runner executes queries against the data source of their data product,
+// evaluates their assertions and records every execution in the run
+// history. It is shared by the HTTP handlers and the Temporal activity so
+// both behave the same.
+type Runner struct {
+	Sources *datagateway.Sources
+	DB      *database.DB
+	Logger  *slog.Logger
+	// Notifier is optional and alerts on status changes of stored queries.
+	Notifier *notify.Dispatcher
+	// MaxAttempts is how often Temporal attempts a failing activity. Runs
+	// that fail before the last attempt are retried and not alerted on;
+	// zero alerts on every attempt.
+	MaxAttempts int32
+
+	notifications sync.WaitGroup
+}
+
+// Permanent reports whether err rejects the query itself, so that running
+// it again cannot help.
+func Permanent(err error) bool {
+	return datagateway.IsPermanent(err) || utility.IsUnsafeQuery(err) || errors.Is(err, templates.ErrPostgresOnly)
+}
+
+// Series is the value of one value column in one row of a query that maps
+// result columns to labels.
+type Series struct {
+	Column     string             `json:"column"`
+	Labels     map[string]string  `json:"labels"`
+	Value      float64            `json:"value"`
+	Status     assertion.Status   `json:"status,omitempty"`
+	Assertions []assertion.Result `json:"assertions,omitempty"`
+}
+
+// Result is the outcome of a run. Single-value queries either have a
+// Value, return NULL (NoValue) or fail to produce a value (ValueError).
+type Result struct {
+	RunID      uuid.UUID                `json:"run_id"`
+	Rows       []map[string]interface{} `json:"rows"`
+	Value      *float64                 `json:"value,omitempty"`
+	NoValue    bool                     `json:"no_value,omitempty"`
+	ValueError string                   `json:"value_error,omitempty"`
+	Series     []Series                 `json:"series,omitempty"`
+	Status     assertion.Status         `json:"status,omitempty"`
+	Assertions []assertion.Result       `json:"assertions,omitempty"`
+	Anomaly    *anomaly.Result          `json:"anomaly,omitempty"`
+}
+
+// maxSeasonalHistory bounds the runs loaded for seasonal anomaly models of
+// frequently scheduled queries.
+const maxSeasonalHistory = 5000
+
+// Run formats and executes query with query.Parameters. Queries with value
+// columns produce one series per row and value column, each evaluated
+// against query.Assertions; other queries are evaluated when they return a
+// single numeric value. Errors from formatting the query or from the gateway
+// are returned as is.
+func (rn *Runner) Run(ctx context.Context, query database.Query) (*Result, error) {
+	run := &database.QueryRun{
+		QueryID:       uuid.NullUUID{UUID: query.QueryID, Valid: query.QueryID != uuid.Nil},
+		Name:          query.Name,
+		DataProductID: query.DataProductID,
+		Parameters:    query.Parameters,
+		StartedAt:     time.Now(),
+	}
+	if activity.IsActivity(ctx) {
+		run.WorkflowID = activity.GetInfo(ctx).WorkflowExecution.ID
+	}
+
+	// earlier attempts of the same workflow execution were not alerted on
+	var previous *database.QueryRun
+	if rn.Notifier != nil && run.QueryID.Valid {
+		var fetchErr error
+		previous, _, fetchErr = rn.DB.GetLatestRun(ctx, query.QueryID, run.WorkflowID)
+		if fetchErr != nil {
+			rn.Logger.Error("could not fetch latest query run", slog.Any("name", query.Name), slog.Any("err", fetchErr))
+		}
+	}
+
+	result, err := rn.run(ctx, query, run)
+
+	run.DurationMS = time.Since(run.StartedAt).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.Value = result.Value
+		run.Status = string(result.Status)
+	}
+
+	insertErr := rn.DB.InsertQueryRun(context.WithoutCancel(ctx), run)
+	if insertErr != nil {
+		rn.Logger.Error("could not record query run", slog.Any("name", query.Name), slog.Any("err", insertErr))
+	}
+	if result != nil {
+		result.RunID = run.RunID
+	}
+
+	if rn.Notifier != nil && run.QueryID.Valid && rn.final(ctx, err) {
+		// channels may take seconds each, the caller does not wait for them
+		event := notificationEvent(run, previous, result)
+		notifyCtx := context.WithoutCancel(ctx)
+		rn.notifications.Add(1)
+		go func() {
+			defer rn.notifications.Done()
+			rn.Notifier.Dispatch(notifyCtx, event)
+		}()
+	}
+
+	return result, err
+}
+
+// Wait blocks until the notifications of finished runs are sent.
+func (rn *Runner) Wait() {
+	rn.notifications.Wait()
+}
+
+// final reports whether a run that returned err is its last attempt, so
+// that a check does not alert and resolve while Temporal retries it.
+func (rn *Runner) final(ctx context.Context, err error) bool {
+	if err == nil || Permanent(err) || !activity.IsActivity(ctx) {
+		return true
+	}
+	return rn.MaxAttempts == 0 || activity.GetInfo(ctx).Attempt >= rn.MaxAttempts
+}
+
+// notificationEvent describes run to notification channels.
+func notificationEvent(run *database.QueryRun, previous *database.QueryRun, result *Result) notify.Event {
+	event := notify.Event{
+		QueryID:       run.QueryID.UUID,
+		RunID:         run.RunID,
+		Name:          run.Name,
+		DataProductID: run.DataProductID,
+		Status:        runStatus(run),
+		Value:         run.Value,
+		Message:       run.Error,
+		Time:          run.StartedAt,
+	}
+	if previous != nil {
+		event.PreviousStatus = runStatus(previous)
+	}
+	if result != nil {
+		event.Message = failureMessage(result)
+	}
+
+	return event
+}
+
+// runStatus is the status a run is alerted on: failed executions count as
+// FAIL and checks without assertions as PASS.
+func runStatus(run *database.QueryRun) assertion.Status {
+	switch {
+	case run.Error != "":
+		return assertion.StatusFail
+	case run.Status == "":
+		return assertion.StatusPass
+	default:
+		return assertion.Status(run.Status)
+	}
+}
+
+// failureMessage summarises the violated assertions and anomaly of result.
+func failureMessage(result *Result) string {
+	var messages []string
+	add := func(results []assertion.Result) {
+		for _, r := range results {
+			if r.Status != assertion.StatusPass && r.Message != "" {
+				messages = append(messages, r.Message)
+			}
+		}
+	}
+
+	add(result.Assertions)
+	for _, series := range result.Series {
+		add(series.Assertions)
+	}
+	if result.Anomaly != nil && result.Anomaly.Anomalous && result.Value != nil {
+		messages = append(messages, fmt.Sprintf("value %v is outside the expected range [%v, %v]", *result.Value, result.Anomaly.Lower, result.Anomaly.Upper))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Render prepares query the way Run sends it to the data source of its
+// data product, without running it or recording a run.
+func (rn *Runner) Render(ctx context.Context, query database.Query) (*utility.Rendering, error) {
+	_, bind, err := rn.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return utility.RenderQuery(query.Query, query.Parameters, bind)
+}
+
+// prepare checks query against the SQL guard and returns the data source of
+// its data product and whether the source binds parameters.
+func (rn *Runner) prepare(ctx context.Context, query database.Query) (datagateway.DataSource, bool, error) {
+	// stored queries were checked when they were saved, but the allowlist
+	// of their data product may have changed since
+	allowedTables, err := rn.DB.ListAllowedTables(ctx, query.DataProductID)
+	if err != nil {
+		return nil, false, err
+	}
+	err = utility.CheckReadOnly(query.Query, allowedTables)
+	if err != nil {
+		return nil, false, err
+	}
+
+	source, sourceType, err := rn.dataSource(ctx, query.DataProductID)
+	if err != nil {
+		return nil, false, err
+	}
+	// gateways are assumed to front Postgres
+	if query.Template != nil && query.Template.PostgresOnly() && (sourceType == datagateway.TypeSQLite || sourceType == datagateway.TypeDuckDB) {
+		return nil, false, fmt.Errorf("%w: %s cannot run on the %s data source of the data product", templates.ErrPostgresOnly, query.Template.Type, sourceType)
+	}
+
+	// only gateways may be unable to bind parameters
+	bind := true
+	if gateway, ok := source.(*datagateway.Gateway); ok {
+		bind = gateway.BindParameters
+	}
+
+	return source, bind, nil
+}
+
+func (rn *Runner) run(ctx context.Context, query database.Query, run *database.QueryRun) (*Result, error) {
+	source, bind, err := rn.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	queryStr, args, err := utility.PrepareQuery(query.Query, query.Parameters, bind)
+	if err != nil {
+		return nil, err
+	}
+	run.SQLHash = hashQuery(queryStr, args)
+
+	rows, err := source.Query(ctx, queryStr, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *float64
+	if len(query.ValueColumns) == 0 && len(query.Assertions) > 0 {
+		previous, err = rn.DB.GetPreviousRunValue(ctx, query.QueryID, query.DataProductID, query.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch previous run: %w", err)
+		}
+	}
+
+	result, err := Evaluate(query, rows, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(query.ValueColumns) > 0 {
+		run.Series, err = json.Marshal(result.Series)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	if result.Value != nil && query.Anomaly != nil && query.QueryID != uuid.Nil {
+		result.Anomaly, err = rn.detectAnomaly(ctx, query, *result.Value, run.StartedAt)
+		if err != nil {
+			return nil, err
+		}
+		if result.Anomaly != nil && result.Anomaly.Anomalous {
+			status := assertion.StatusWarn
+			if query.Anomaly.Severity == assertion.SeverityFail {
+				status = assertion.StatusFail
+			}
+			if status.Code() > result.Status.Code() || result.Status == "" {
+				result.Status = status
+			}
+		} else if result.Anomaly != nil && result.Status == "" {
+			result.Status = assertion.StatusPass
+		}
+	}
+
+	return result, nil
+}
+
+// Evaluate extracts the value, or the series of queries with value
+// columns, from the rows query returned and evaluates query.Assertions
+// against it. previous is the value of the last run of a single-value
+// query, nil if there is none.
+func Evaluate(query database.Query, rows []map[string]interface{}, previous *float64) (*Result, error) {
+	result := &Result{Rows: rows}
+
+	if len(query.ValueColumns) > 0 {
+		var err error
+		result.Series, err = extractSeries(rows, query.LabelColumns, query.ValueColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(query.Assertions) > 0 {
+			// change_percent only applies to single-value queries, there is
+			// no per-series history to compare against
+			result.Status = assertion.StatusPass
+			for i := range result.Series {
+				series := &result.Series[i]
+				series.Status, series.Assertions = query.Assertions.Evaluate(series.Value, nil)
+				if series.Status.Code() > result.Status.Code() {
+					result.Status = series.Status
+				}
+			}
+		}
+		return result, nil
+	}
+
+	value, err := singleValue(rows)
+	result.Value = value
+	switch {
+	case err != nil:
+		result.ValueError = err.Error()
+	case result.Value == nil:
+		result.NoValue = true
+		err = errNoValue
+	}
+
+	if len(query.Assertions) > 0 {
+		if result.Value == nil {
+			result.Status = assertion.StatusFail
+			result.Assertions = []assertion.Result{{
+				Status:  assertion.StatusFail,
+				Message: fmt.Sprintf("assertions could not be evaluated: %v", err),
+			}}
+		} else {
+			result.Status, result.Assertions = query.Assertions.Evaluate(*result.Value, previous)
+		}
+	}
+
+	return result, nil
+}
+
+// dataSource returns the data source configured for the data product and
+// its type, or the default one.
+func (rn *Runner) dataSource(ctx context.Context, dataProductID uuid.UUID) (datagateway.DataSource, string, error) {
+	config, found, err := rn.DB.GetDataSource(ctx, dataProductID)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not fetch data source: %w", err)
+	}
+	if !found {
+		return rn.Sources.Default, datagateway.TypeGateway, nil
+	}
+	source, err := rn.Sources.Get(config.Type, config.Config)
+	return source, config.Type, err
+}
+
+// detectAnomaly compares value with the run history of query. It returns
+// nil when there is not enough history for a verdict.
+func (rn *Runner) detectAnomaly(ctx context.Context, query database.Query, value float64, at time.Time) (*anomaly.Result, error) {
+	model := query.Anomaly.Defaults()
+
+	limit := model.Window
+	if model.Type == anomaly.TypeSeasonal {
+		limit = maxSeasonalHistory
+	}
+
+	values, err := rn.DB.ListRunValues(ctx, query.QueryID, model.Since(at), limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch run history: %w", err)
+	}
+
+	history := make([]anomaly.Point, 0, len(values))
+	for _, v := range values {
+		history = append(history, anomaly.Point{Value: v.Value, At: v.StartedAt})
+	}
+
+	result, ok := model.Evaluate(value, at, history)
+	if !ok {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// hashQuery identifies the exact statement sent to the gateway, including
+// its bound parameters.
+func hashQuery(queryStr string, args []interface{}) string {
+	h := sha256.New()
+	h.Write([]byte(queryStr))
+	if args != nil {
+		js, _ := json.Marshal(args)
+		h.Write(js)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// singleValue returns the only value of rows, or nil when it is NULL.
+func singleValue(rows []map[string]interface{}) (*float64, error) {
+	if len(rows) != 1 || len(rows[0]) != 1 {
+		return nil, ErrNotSingleValue
+	}
+
+	for _, v := range rows[0] {
+		value, ok, err := Numeric(v)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return &value, nil
+	}
+
+	return nil, ErrNotSingleValue
+}
+
+func extractSeries(rows []map[string]interface{}, labelColumns []string, valueColumns []string) ([]Series, error) {
+	series := make([]Series, 0, len(rows)*len(valueColumns))
+
+	// rows with the same labels would export the same series twice, which
+	// fails the whole scrape
+	seen := make(map[string]int, len(rows))
+
+	for i, row := range rows {
+		labels := make(map[string]string, len(labelColumns))
+		key := make([]string, 0, len(labelColumns))
+		for _, column := range labelColumns {
+			v, ok := row[column]
+			if !ok {
+				return nil, fmt.Errorf("row %d has no label column %q", i, column)
+			}
+			labels[column] = labelValue(v)
+			key = append(key, labels[column])
+		}
+		id := strings.Join(key, "\x00")
+		if first, ok := seen[id]; ok {
+			return nil, fmt.Errorf("rows %d and %d have the same labels %v, label columns must identify a row", first, i, labels)
+		}
+		seen[id] = i
+
+		for _, column := range valueColumns {
+			v, ok := row[column]
+			if !ok {
+				return nil, fmt.Errorf("row %d has no value column %q", i, column)
+			}
+			value, ok, err := Numeric(v)
+			if err != nil {
+				return nil, fmt.Errorf("row %d column %q: %w", i, column, err)
+			}
+			if !ok {
+				// NULL, there is no value to export for this series
+				continue
+			}
+			series = append(series, Series{Column: column, Labels: labels, Value: value})
+		}
+	}
+
+	return series, nil
+}
+
+func labelValue(v interface{}) string {
+	switch y := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return y
+	case json.Number:
+		return y.String()
+	case float64:
+		return strconv.FormatFloat(y, 'f', -1, 64)
+	default:
+		return fmt.Sprint(y)
+	}
+}
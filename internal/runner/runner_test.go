@@ -0,0 +1,66 @@
+package runner_test
+
+import (
+	"strings"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
+)
+
+func TestEvaluateSeries(t *testing.T) {
+	query := database.Query{
+		LabelColumns: []string{"region", "status"},
+		ValueColumns: []string{"n"},
+	}
+
+	tests := []struct {
+		name       string
+		rows       []map[string]interface{}
+		wantSeries int
+		wantErr    string
+	}{
+		{
+			name: "distinct labels",
+			rows: []map[string]interface{}{
+				{"region": "eu", "status": "open", "n": int64(3)},
+				{"region": "eu", "status": "closed", "n": int64(5)},
+				{"region": "us", "status": "open", "n": nil},
+			},
+			wantSeries: 2,
+		},
+		{
+			name: "duplicate labels",
+			rows: []map[string]interface{}{
+				{"region": "eu", "status": "open", "n": int64(3)},
+				{"region": "us", "status": "open", "n": int64(4)},
+				{"region": "eu", "status": "open", "n": int64(5)},
+			},
+			wantErr: "rows 0 and 2 have the same labels",
+		},
+		{
+			name: "missing label column",
+			rows: []map[string]interface{}{
+				{"region": "eu", "n": int64(3)},
+			},
+			wantErr: `row 0 has no label column "status"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runner.Evaluate(query, tt.rows, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Series) != tt.wantSeries {
+				t.Errorf("got %d series, want %d", len(result.Series), tt.wantSeries)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Numeric converts a value from a query result into the float64 a metric
+// is exported as. It reports false, without an error, for NULL. Besides Go
+// numbers it accepts json.Number, numeric strings such as decimals
+// returned as text, and booleans as 1 or 0.
+func Numeric(v interface{}) (float64, bool, error) {
+	switch y := v.(type) {
+	case nil:
+		return 0, false, nil
+	case int:
+		return float64(y), true, nil
+	case int8:
+		return float64(y), true, nil
+	case int16:
+		return float64(y), true, nil
+	case int32:
+		return float64(y), true, nil
+	case int64:
+		return float64(y), true, nil
+	case uint:
+		return float64(y), true, nil
+	case uint8:
+		return float64(y), true, nil
+	case uint16:
+		return float64(y), true, nil
+	case uint32:
+		return float64(y), true, nil
+	case uint64:
+		return float64(y), true, nil
+	case float32:
+		return float64(y), true, nil
+	case float64:
+		return y, true, nil
+	case bool:
+		if y {
+			return 1, true, nil
+		}
+		return 0, true, nil
+	case json.Number:
+		return parseNumeric(string(y), v)
+	case string:
+		return parseNumeric(y, v)
+	case []byte:
+		return parseNumeric(string(y), v)
+	default:
+		return 0, false, fmt.Errorf("query returns non-numeric type %T: %v", v, v)
+	}
+}
+
+func parseNumeric(s string, v interface{}) (float64, bool, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	// strconv also accepts "NaN" and "Inf", which are not numbers a query
+	// is expected to return as text
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false, fmt.Errorf("query returns non-numeric value: %q", v)
+	}
+	return f, true, nil
+}
@@ -0,0 +1,50 @@
+package runner_test
+
+import (
+	"encoding/json"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
+)
+
+func TestNumeric(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    float64
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "null", value: nil},
+		{name: "int", value: 42, want: 42, wantOK: true},
+		{name: "int32", value: int32(-7), want: -7, wantOK: true},
+		{name: "uint64", value: uint64(18), want: 18, wantOK: true},
+		{name: "float32", value: float32(0.5), want: 0.5, wantOK: true},
+		{name: "float64", value: 1.25, want: 1.25, wantOK: true},
+		{name: "true", value: true, want: 1, wantOK: true},
+		{name: "false", value: false, want: 0, wantOK: true},
+		{name: "json number", value: json.Number("9007199254740993"), want: 9007199254740993, wantOK: true},
+		{name: "decimal string", value: "12.50", want: 12.5, wantOK: true},
+		{name: "padded string", value: " 3 ", want: 3, wantOK: true},
+		{name: "bytes", value: []byte("0.001"), want: 0.001, wantOK: true},
+		{name: "text", value: "abc", wantErr: true},
+		{name: "NaN", value: "NaN", wantErr: true},
+		{name: "infinity", value: json.Number("Inf"), wantErr: true},
+		{name: "empty string", value: "", wantErr: true},
+		{name: "object", value: map[string]interface{}{"a": 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := runner.Numeric(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
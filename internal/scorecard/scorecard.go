@@ -0,0 +1,154 @@
+// Package scorecard rates the health of a data product from the outcome of
+// its checks, overall and per quality dimension.
+package scorecard
+
+import (
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DimensionCompleteness = "completeness"
+	DimensionValidity     = "validity"
+	DimensionFreshness    = "freshness"
+	DimensionUniqueness   = "uniqueness"
+)
+
+// Dimensions lists the quality dimensions checks can be assigned to, in
+// the order they are reported.
+var Dimensions = []string{DimensionCompleteness, DimensionValidity, DimensionFreshness, DimensionUniqueness}
+
+// ValidDimension reports whether dimension is one of Dimensions or empty,
+// which leaves a check unassigned.
+func ValidDimension(dimension string) bool {
+	if dimension == "" {
+		return true
+	}
+	for _, d := range Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateDimension returns the dimension checks built from a template of
+// templateType measure, or "" if there is none.
+func TemplateDimension(templateType string) string {
+	switch templateType {
+	case templates.TypeRowCount, templates.TypeNullRate:
+		return DimensionCompleteness
+	case templates.TypeUniqueness:
+		return DimensionUniqueness
+	case templates.TypeFreshness:
+		return DimensionFreshness
+	case templates.TypeReferentialIntegrity, templates.TypeAcceptedValues, templates.TypeRegex:
+		return DimensionValidity
+	}
+	return ""
+}
+
+// Check is the outcome of one check of a data product. Checks that passed
+// score 100, with warnings 50 and checks that failed or could not run 0.
+// Checks without assertions have no status and are not scored.
+type Check struct {
+	QueryID   uuid.UUID        `json:"query_id"`
+	Name      string           `json:"name"`
+	Dimension string           `json:"dimension,omitempty"`
+	Weight    float64          `json:"weight"`
+	RunID     uuid.UUID        `json:"run_id,omitempty"`
+	Status    assertion.Status `json:"status,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Score     *float64         `json:"score"`
+}
+
+// Dimension is the score of the checks of one dimension. Score is nil when
+// none of them was scored.
+type Dimension struct {
+	Dimension string   `json:"dimension"`
+	Score     *float64 `json:"score"`
+	Weight    float64  `json:"weight"`
+	Checks    int      `json:"checks"`
+}
+
+// Scorecard is the health of a data product: the weighted mean score of
+// all its scored checks, assigned to a dimension or not, and of the checks
+// of each dimension.
+type Scorecard struct {
+	DataProductID uuid.UUID   `json:"data_product_id"`
+	Score         *float64    `json:"score"`
+	Dimensions    []Dimension `json:"dimensions"`
+	Checks        []Check     `json:"checks"`
+	ComputedAt    time.Time   `json:"computed_at"`
+}
+
+// Compute scores checks and aggregates them into the scorecard of the data
+// product.
+func Compute(dataProductID uuid.UUID, checks []Check) *Scorecard {
+	card := &Scorecard{
+		DataProductID: dataProductID,
+		Checks:        checks,
+		ComputedAt:    time.Now(),
+	}
+
+	dimensions := make(map[string]*Dimension, len(Dimensions))
+	sums := make(map[string]float64, len(Dimensions))
+	for _, d := range Dimensions {
+		dimensions[d] = &Dimension{Dimension: d}
+	}
+
+	var total, totalWeight float64
+	for i := range checks {
+		c := &checks[i]
+		if d, ok := dimensions[c.Dimension]; ok {
+			d.Checks++
+		}
+
+		score, ok := checkScore(*c)
+		if !ok {
+			continue
+		}
+		c.Score = &score
+
+		total += score * c.Weight
+		totalWeight += c.Weight
+		if d, ok := dimensions[c.Dimension]; ok {
+			sums[c.Dimension] += score * c.Weight
+			d.Weight += c.Weight
+		}
+	}
+
+	card.Score = mean(total, totalWeight)
+	for _, name := range Dimensions {
+		d := dimensions[name]
+		d.Score = mean(sums[name], d.Weight)
+		card.Dimensions = append(card.Dimensions, *d)
+	}
+	return card
+}
+
+func checkScore(c Check) (float64, bool) {
+	if c.Error != "" {
+		return 0, true
+	}
+	switch c.Status {
+	case assertion.StatusPass:
+		return 100, true
+	case assertion.StatusWarn:
+		return 50, true
+	case assertion.StatusFail:
+		return 0, true
+	}
+	return 0, false
+}
+
+func mean(sum float64, weight float64) *float64 {
+	if weight <= 0 {
+		return nil
+	}
+	score := sum / weight
+	return &score
+}
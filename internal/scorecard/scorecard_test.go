@@ -0,0 +1,81 @@
+package scorecard_test
+
+import (
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/assertion"
+	"xcaliber/data-quality-metrics-framework/internal/scorecard"
+
+	"github.com/google/uuid"
+)
+
+func TestCompute(t *testing.T) {
+	checks := []scorecard.Check{
+		{Name: "nulls", Dimension: scorecard.DimensionCompleteness, Weight: 3, Status: assertion.StatusPass},
+		{Name: "rows", Dimension: scorecard.DimensionCompleteness, Weight: 1, Status: assertion.StatusFail},
+		{Name: "lag", Dimension: scorecard.DimensionFreshness, Weight: 1, Status: assertion.StatusWarn},
+		{Name: "duplicates", Dimension: scorecard.DimensionUniqueness, Weight: 2, Error: "gateway down"},
+		{Name: "custom", Weight: 2, Status: assertion.StatusPass},
+		{Name: "informational", Dimension: scorecard.DimensionValidity, Weight: 1},
+	}
+
+	card := scorecard.Compute(uuid.New(), checks)
+
+	// (3*100 + 1*0 + 1*50 + 2*0 + 2*100) / 9
+	if card.Score == nil || *card.Score != 550.0/9 {
+		t.Errorf("got score %v, want %v", card.Score, 550.0/9)
+	}
+
+	want := map[string]struct {
+		score  *float64
+		checks int
+	}{
+		scorecard.DimensionCompleteness: {score: ptr(75), checks: 2},
+		scorecard.DimensionValidity:     {score: nil, checks: 1},
+		scorecard.DimensionFreshness:    {score: ptr(50), checks: 1},
+		scorecard.DimensionUniqueness:   {score: ptr(0), checks: 1},
+	}
+	if len(card.Dimensions) != len(scorecard.Dimensions) {
+		t.Fatalf("got %d dimensions, want %d", len(card.Dimensions), len(scorecard.Dimensions))
+	}
+	for i, d := range card.Dimensions {
+		if d.Dimension != scorecard.Dimensions[i] {
+			t.Errorf("dimension %d is %s, want %s", i, d.Dimension, scorecard.Dimensions[i])
+		}
+		w := want[d.Dimension]
+		if d.Checks != w.checks {
+			t.Errorf("%s: got %d checks, want %d", d.Dimension, d.Checks, w.checks)
+		}
+		if (d.Score == nil) != (w.score == nil) || d.Score != nil && *d.Score != *w.score {
+			t.Errorf("%s: got score %v, want %v", d.Dimension, d.Score, w.score)
+		}
+	}
+
+	if card.Checks[5].Score != nil {
+		t.Errorf("check without assertions was scored %v", *card.Checks[5].Score)
+	}
+	if card.Checks[3].Score == nil || *card.Checks[3].Score != 0 {
+		t.Errorf("check that could not run was scored %v, want 0", card.Checks[3].Score)
+	}
+}
+
+func TestComputeWithoutScoredChecks(t *testing.T) {
+	card := scorecard.Compute(uuid.New(), []scorecard.Check{{Name: "rows", Weight: 1}})
+	if card.Score != nil {
+		t.Errorf("got score %v, want none", *card.Score)
+	}
+}
+
+func TestValidDimension(t *testing.T) {
+	for _, d := range append([]string{""}, scorecard.Dimensions...) {
+		if !scorecard.ValidDimension(d) {
+			t.Errorf("%q is not valid", d)
+		}
+	}
+	if scorecard.ValidDimension("accuracy") {
+		t.Error("accuracy is valid")
+	}
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,224 @@
+package templates
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	TypeRowCount             = "row_count"
+	TypeNullRate             = "null_rate"
+	TypeUniqueness           = "uniqueness"
+	TypeFreshness            = "freshness"
+	TypeReferentialIntegrity = "referential_integrity"
+	TypeAcceptedValues       = "accepted_values"
+	TypeRegex                = "regex"
+)
+
+// parameterPrefix marks the parameters a template adds for its values so
+// they do not clash with the parameters of the filter.
+const parameterPrefix = "tpl_"
+
+// ErrPostgresOnly is reported for templates whose SQL only Postgres runs
+// when the data source of their data product is another database.
+var ErrPostgresOnly = errors.New("template generates SQL that only Postgres runs")
+
+// Type describes a check template and the value its query returns.
+// PostgresOnly templates cannot run on SQLite or DuckDB data sources.
+type Type struct {
+	Type         string   `json:"type"`
+	Description  string   `json:"description"`
+	Required     []string `json:"required"`
+	PostgresOnly bool     `json:"postgres_only"`
+}
+
+// Types lists the supported templates. freshness relies on now() and the
+// EXTRACT(EPOCH ...) of an interval, regex on the POSIX !~ operator, both
+// of which only Postgres has.
+var Types = []Type{
+	{TypeRowCount, "Number of rows in the table", []string{"table"}, false},
+	{TypeNullRate, "Fraction of rows, between 0 and 1, in which the column is NULL", []string{"table", "column"}, false},
+	{TypeUniqueness, "Number of key values that occur in more than one row", []string{"table", "columns"}, false},
+	{TypeFreshness, "Seconds since the latest value of the timestamp column", []string{"table", "column"}, true},
+	{TypeReferentialIntegrity, "Number of rows whose column value has no match in the reference table", []string{"table", "column", "reference_table", "reference_column"}, false},
+	{TypeAcceptedValues, "Number of rows whose column value is not one of the accepted values", []string{"table", "column", "values"}, false},
+	{TypeRegex, "Number of rows whose column value does not match the regular expression", []string{"table", "column", "pattern"}, true},
+}
+
+// PostgresOnly reports whether the SQL of the template only runs on
+// Postgres, see Types.
+func (s Spec) PostgresOnly() bool {
+	for _, t := range Types {
+		if t.Type == s.Type {
+			return t.PostgresOnly
+		}
+	}
+	return false
+}
+
+// Spec describes a check built from a template. Filter is an optional SQL
+// condition restricting the rows that are checked; it may reference query
+// parameters such as $start_date. NULL values are not counted as violations
+// by the accepted_values, regex and referential_integrity templates.
+type Spec struct {
+	Type            string        `json:"type"`
+	Table           string        `json:"table"`
+	Column          string        `json:"column,omitempty"`
+	Columns         []string      `json:"columns,omitempty"`
+	Filter          string        `json:"filter,omitempty"`
+	ReferenceTable  string        `json:"reference_table,omitempty"`
+	ReferenceColumn string        `json:"reference_column,omitempty"`
+	Values          []interface{} `json:"values,omitempty"`
+	Pattern         string        `json:"pattern,omitempty"`
+}
+
+func (s Spec) Validate() error {
+	if s.Table == "" {
+		return errors.New("template requires table")
+	}
+
+	switch s.Type {
+	case TypeRowCount:
+	case TypeNullRate, TypeFreshness:
+		if s.Column == "" {
+			return fmt.Errorf("%s template requires column", s.Type)
+		}
+	case TypeUniqueness:
+		if len(s.Columns) == 0 {
+			return errors.New("uniqueness template requires columns")
+		}
+		for _, column := range s.Columns {
+			if column == "" {
+				return errors.New("uniqueness template columns must not be empty")
+			}
+		}
+	case TypeReferentialIntegrity:
+		if s.Column == "" || s.ReferenceTable == "" || s.ReferenceColumn == "" {
+			return errors.New("referential_integrity template requires column, reference_table and reference_column")
+		}
+	case TypeAcceptedValues:
+		if s.Column == "" || len(s.Values) == 0 {
+			return errors.New("accepted_values template requires column and values")
+		}
+		for _, v := range s.Values {
+			switch v.(type) {
+			case string, float64, bool:
+			default:
+				return fmt.Errorf("accepted_values template values must be strings, numbers or booleans, got %v", v)
+			}
+		}
+	case TypeRegex:
+		if s.Column == "" || s.Pattern == "" {
+			return errors.New("regex template requires column and pattern")
+		}
+	default:
+		return fmt.Errorf("unsupported template type %q", s.Type)
+	}
+
+	return nil
+}
+
+// Render generates the SQL for the check. Identifiers are quoted and the
+// accepted values and pattern are returned as parameters referenced by
+// $placeholders, so the query goes through the same formatting or binding
+// as hand-written queries.
+func (s Spec) Render() (string, json.RawMessage, error) {
+	err := s.Validate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	table := quoteTable(s.Table)
+	column := quoteIdentifier(s.Column)
+	parameters := map[string]interface{}{}
+
+	var query string
+	switch s.Type {
+	case TypeRowCount:
+		query = "SELECT count(*) FROM " + table + where(s.Filter)
+	case TypeNullRate:
+		query = "SELECT COALESCE(avg(CASE WHEN " + column + " IS NULL THEN 1.0 ELSE 0.0 END), 0) FROM " + table + where(s.Filter)
+	case TypeUniqueness:
+		columns := make([]string, len(s.Columns))
+		for i, c := range s.Columns {
+			columns[i] = quoteIdentifier(c)
+		}
+		query = "SELECT count(*) FROM (SELECT 1 FROM " + table + where(s.Filter) +
+			" GROUP BY " + strings.Join(columns, ", ") + " HAVING count(*) > 1) duplicates"
+	case TypeFreshness:
+		query = "SELECT EXTRACT(EPOCH FROM (now() - max(" + column + "))) FROM " + table + where(s.Filter)
+	case TypeReferentialIntegrity:
+		query = "SELECT count(*) FROM " + table + " child" +
+			where(s.Filter, "child."+column+" IS NOT NULL",
+				"NOT EXISTS (SELECT 1 FROM "+quoteTable(s.ReferenceTable)+" parent WHERE parent."+quoteIdentifier(s.ReferenceColumn)+" = child."+column+")")
+	case TypeAcceptedValues:
+		placeholders := make([]string, len(s.Values))
+		for i, v := range s.Values {
+			name := fmt.Sprintf("%svalue_%d", parameterPrefix, i)
+			parameters[name] = v
+			placeholders[i] = "$" + name
+		}
+		query = "SELECT count(*) FROM " + table +
+			where(s.Filter, column+" IS NOT NULL", column+" NOT IN ("+strings.Join(placeholders, ", ")+")")
+	case TypeRegex:
+		parameters[parameterPrefix+"pattern"] = s.Pattern
+		query = "SELECT count(*) FROM " + table +
+			where(s.Filter, column+" IS NOT NULL", "CAST("+column+" AS text) !~ $"+parameterPrefix+"pattern")
+	}
+
+	js, err := json.Marshal(parameters)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, js, nil
+}
+
+// where joins the filter and conditions into a WHERE clause, or returns an
+// empty string when there are none.
+func where(filter string, conditions ...string) string {
+	if filter != "" {
+		conditions = append([]string{"(" + filter + ")"}, conditions...)
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteTable quotes each part of a possibly schema-qualified table name.
+func quoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (s *Spec) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
+
+func (s *Spec) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into Spec", src)
+	}
+}
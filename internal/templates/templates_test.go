@@ -0,0 +1,166 @@
+package templates_test
+
+import (
+	"encoding/json"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/templates"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name           string
+		spec           templates.Spec
+		wantQuery      string
+		wantParameters string
+		wantErr        bool
+	}{
+		{
+			name:           "row count",
+			spec:           templates.Spec{Type: templates.TypeRowCount, Table: "public.orders"},
+			wantQuery:      `SELECT count(*) FROM "public"."orders"`,
+			wantParameters: `{}`,
+		},
+		{
+			name:           "row count with filter",
+			spec:           templates.Spec{Type: templates.TypeRowCount, Table: "orders", Filter: "created_at >= $start"},
+			wantQuery:      `SELECT count(*) FROM "orders" WHERE (created_at >= $start)`,
+			wantParameters: `{}`,
+		},
+		{
+			name:           "null rate",
+			spec:           templates.Spec{Type: templates.TypeNullRate, Table: "orders", Column: "email"},
+			wantQuery:      `SELECT COALESCE(avg(CASE WHEN "email" IS NULL THEN 1.0 ELSE 0.0 END), 0) FROM "orders"`,
+			wantParameters: `{}`,
+		},
+		{
+			name:           "uniqueness",
+			spec:           templates.Spec{Type: templates.TypeUniqueness, Table: "orders", Columns: []string{"tenant_id", "order_id"}},
+			wantQuery:      `SELECT count(*) FROM (SELECT 1 FROM "orders" GROUP BY "tenant_id", "order_id" HAVING count(*) > 1) duplicates`,
+			wantParameters: `{}`,
+		},
+		{
+			name:           "freshness",
+			spec:           templates.Spec{Type: templates.TypeFreshness, Table: "orders", Column: "updated_at"},
+			wantQuery:      `SELECT EXTRACT(EPOCH FROM (now() - max("updated_at"))) FROM "orders"`,
+			wantParameters: `{}`,
+		},
+		{
+			name: "referential integrity",
+			spec: templates.Spec{
+				Type:            templates.TypeReferentialIntegrity,
+				Table:           "orders",
+				Column:          "customer_id",
+				ReferenceTable:  "customers",
+				ReferenceColumn: "id",
+			},
+			wantQuery: `SELECT count(*) FROM "orders" child WHERE child."customer_id" IS NOT NULL AND ` +
+				`NOT EXISTS (SELECT 1 FROM "customers" parent WHERE parent."id" = child."customer_id")`,
+			wantParameters: `{}`,
+		},
+		{
+			name:           "accepted values",
+			spec:           templates.Spec{Type: templates.TypeAcceptedValues, Table: "orders", Column: "status", Values: []interface{}{"open", "closed"}},
+			wantQuery:      `SELECT count(*) FROM "orders" WHERE "status" IS NOT NULL AND "status" NOT IN ($tpl_value_0, $tpl_value_1)`,
+			wantParameters: `{"tpl_value_0":"open","tpl_value_1":"closed"}`,
+		},
+		{
+			name:           "regex",
+			spec:           templates.Spec{Type: templates.TypeRegex, Table: "orders", Column: "zip", Pattern: `^\d{5}$`},
+			wantQuery:      `SELECT count(*) FROM "orders" WHERE "zip" IS NOT NULL AND CAST("zip" AS text) !~ $tpl_pattern`,
+			wantParameters: `{"tpl_pattern":"^\\d{5}$"}`,
+		},
+		{
+			name:           "quoted identifiers",
+			spec:           templates.Spec{Type: templates.TypeNullRate, Table: `odd"table`, Column: `a"b`},
+			wantQuery:      `SELECT COALESCE(avg(CASE WHEN "a""b" IS NULL THEN 1.0 ELSE 0.0 END), 0) FROM "odd""table"`,
+			wantParameters: `{}`,
+		},
+		{
+			name:    "missing column",
+			spec:    templates.Spec{Type: templates.TypeNullRate, Table: "orders"},
+			wantErr: true,
+		},
+		{
+			name:    "missing table",
+			spec:    templates.Spec{Type: templates.TypeRowCount},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported value",
+			spec:    templates.Spec{Type: templates.TypeAcceptedValues, Table: "orders", Column: "status", Values: []interface{}{nil}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			spec:    templates.Spec{Type: "distribution", Table: "orders"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, parameters, err := tt.spec.Render()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if query != tt.wantQuery {
+				t.Errorf("got query\n%s\nwant\n%s", query, tt.wantQuery)
+			}
+			if string(parameters) != tt.wantParameters {
+				t.Errorf("got parameters %s, want %s", parameters, tt.wantParameters)
+			}
+			if err := utility.CheckReadOnly(query, nil); err != nil {
+				t.Errorf("generated query is rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderBinds(t *testing.T) {
+	spec := templates.Spec{Type: templates.TypeAcceptedValues, Table: "orders", Column: "status", Values: []interface{}{"open", float64(3)}}
+
+	query, parameters, err := spec.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bound, args, err := utility.BindQuery(query, parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `SELECT count(*) FROM "orders" WHERE "status" IS NOT NULL AND "status" NOT IN ($1, $2)`
+	if bound != want {
+		t.Errorf("got %s, want %s", bound, want)
+	}
+	js, _ := json.Marshal(args)
+	if string(js) != `["open",3]` {
+		t.Errorf("got args %s", js)
+	}
+}
+
+func TestPostgresOnly(t *testing.T) {
+	tests := []struct {
+		templateType string
+		want         bool
+	}{
+		{templates.TypeRowCount, false},
+		{templates.TypeAcceptedValues, false},
+		{templates.TypeFreshness, true},
+		{templates.TypeRegex, true},
+		{"unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.templateType, func(t *testing.T) {
+			spec := templates.Spec{Type: tt.templateType}
+			if got := spec.PostgresOnly(); got != tt.want {
+				t.Errorf("PostgresOnly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
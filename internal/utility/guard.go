@@ -0,0 +1,444 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotReadOnly is reported for queries that could change data or
+	// schema, or that contain more than one statement.
+	ErrNotReadOnly = errors.New("query is not read-only")
+	// ErrTableNotAllowed is reported for queries that read a table outside
+	// the allowlist of their data product.
+	ErrTableNotAllowed = errors.New("query reads a table that is not allowed")
+)
+
+// IsUnsafeQuery reports whether err is a rejection by CheckReadOnly.
+func IsUnsafeQuery(err error) bool {
+	return errors.Is(err, ErrNotReadOnly) || errors.Is(err, ErrTableNotAllowed)
+}
+
+// deniedKeywords change data, schema or permissions, or lock rows, and are
+// rejected anywhere in a query, e.g. in data-modifying WITH clauses. SELECT
+// INTO creates a table.
+var deniedKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"DROP": true, "CREATE": true, "ALTER": true, "TRUNCATE": true, "COPY": true,
+	"GRANT": true, "REVOKE": true, "INTO": true, "LOCK": true, "CALL": true,
+	"EXECUTE": true,
+}
+
+// deniedFunctions have side effects even in a SELECT. Quoted names are
+// matched as written, since the database does not fold them. The list
+// cannot be complete, so database sources also run queries read-only.
+var deniedFunctions = map[string]bool{
+	"nextval": true, "setval": true, "set_config": true, "pg_notify": true,
+	"pg_terminate_backend": true, "pg_cancel_backend": true, "pg_reload_conf": true,
+	"pg_rotate_logfile": true, "pg_switch_wal": true, "pg_create_restore_point": true,
+	"pg_logical_emit_message": true, "pg_promote": true, "pg_stat_reset": true,
+	"pg_advisory_lock": true, "pg_advisory_lock_shared": true,
+	"pg_advisory_xact_lock": true, "pg_advisory_xact_lock_shared": true,
+	"pg_try_advisory_lock": true, "pg_try_advisory_lock_shared": true,
+	"pg_try_advisory_xact_lock": true, "pg_try_advisory_xact_lock_shared": true,
+	"pg_advisory_unlock": true, "pg_advisory_unlock_shared": true, "pg_advisory_unlock_all": true,
+	"pg_create_physical_replication_slot": true, "pg_create_logical_replication_slot": true,
+	"pg_drop_replication_slot": true, "pg_replication_origin_create": true,
+	"pg_read_file": true, "pg_read_binary_file": true, "pg_ls_dir": true, "pg_stat_file": true,
+	"pg_file_write": true, "pg_file_rename": true, "pg_file_unlink": true,
+	"lo_import": true, "lo_export": true, "lo_unlink": true, "lo_create": true, "lo_creat": true,
+	"lo_put": true, "lo_get": true, "lo_from_bytea": true, "lo_open": true, "lo_truncate": true,
+	"lowrite": true, "loread": true,
+	"dblink": true, "dblink_exec": true, "dblink_connect": true, "dblink_connect_u": true,
+	"dblink_send_query": true, "dblink_open": true, "dblink_fetch": true, "query_to_xml": true,
+	"pg_sleep": true, "pg_sleep_for": true, "pg_sleep_until": true,
+}
+
+// clauseKeywords end the table list of a FROM clause.
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "FETCH": true, "FOR": true, "WINDOW": true, "UNION": true,
+	"INTERSECT": true, "EXCEPT": true, "RETURNING": true, "SELECT": true,
+}
+
+type tokenKind int
+
+const (
+	tokenWord    tokenKind = iota // keyword or unquoted identifier
+	tokenQuoted                   // quoted identifier
+	tokenLiteral                  // string, number or parameter
+	tokenPunct                    // any other character
+)
+
+type sqlToken struct {
+	kind tokenKind
+	text string
+}
+
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (t sqlToken) isPunct(c string) bool {
+	return t.kind == tokenPunct && t.text == c
+}
+
+func (t sqlToken) isName() bool {
+	return t.kind == tokenWord || t.kind == tokenQuoted
+}
+
+// name returns the identifier as the database resolves it: unquoted
+// identifiers are folded to lower case.
+func (t sqlToken) name() string {
+	if t.kind == tokenWord {
+		return strings.ToLower(t.text)
+	}
+	return t.text
+}
+
+// tokenize splits query into tokens, dropping whitespace and comments.
+func tokenize(query string) []sqlToken {
+	var tokens []sqlToken
+
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '\'':
+			escapes := len(tokens) > 0 && tokens[len(tokens)-1].is("E") && i > 0 && query[i-1] != ' '
+			if escapes {
+				// E'...' was read as the word E followed by a literal
+				tokens = tokens[:len(tokens)-1]
+			}
+			i = skipQuoted(query, i, '\'', escapes)
+			tokens = append(tokens, sqlToken{kind: tokenLiteral})
+		case c == '"':
+			end := skipQuoted(query, i, '"', false)
+			text := strings.TrimSuffix(query[i+1:end], `"`)
+			tokens = append(tokens, sqlToken{kind: tokenQuoted, text: strings.ReplaceAll(text, `""`, `"`)})
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+		case c == '$':
+			if tag, ok := dollarQuoteTag(query, i); ok {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end < 0 {
+					i = len(query)
+				} else {
+					i += len(tag) + end + len(tag)
+				}
+				tokens = append(tokens, sqlToken{kind: tokenLiteral})
+				continue
+			}
+			// $name placeholder or $1 positional parameter
+			i++
+			for i < len(query) && isIdentChar(query[i]) && query[i] != '$' {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenLiteral})
+		case isIdentStart(c):
+			start := i
+			for i < len(query) && isIdentChar(query[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenWord, text: query[start:i]})
+		case c >= '0' && c <= '9':
+			for i < len(query) && (isIdentChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenLiteral})
+		default:
+			tokens = append(tokens, sqlToken{kind: tokenPunct, text: string(c)})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// CheckReadOnly reports an ErrNotReadOnly error unless query is a single
+// SELECT, WITH, VALUES or TABLE statement that neither contains DDL or DML
+// keywords nor calls functions with side effects. If allowedTables is not
+// empty, every table the query reads must match one of its entries, given
+// as table, schema.table or schema.* and resolved like identifiers in SQL;
+// otherwise an ErrTableNotAllowed error is reported.
+//
+// The check is lexical. Identifiers that collide with a denied keyword,
+// e.g. a column named update, have to be quoted.
+func CheckReadOnly(query string, allowedTables []string) error {
+	tokens := tokenize(query)
+
+	// a trailing semicolon is fine, anything after it is another statement
+	for i, t := range tokens {
+		if t.isPunct(";") && i < len(tokens)-1 {
+			return fmt.Errorf("%w: multiple statements are not allowed", ErrNotReadOnly)
+		}
+	}
+	if n := len(tokens); n > 0 && tokens[n-1].isPunct(";") {
+		tokens = tokens[:n-1]
+	}
+
+	if len(tokens) == 0 {
+		return fmt.Errorf("%w: query is empty", ErrNotReadOnly)
+	}
+	first := tokens[0]
+	if !first.is("SELECT") && !first.is("WITH") && !first.is("VALUES") && !first.is("TABLE") && !first.isPunct("(") {
+		return fmt.Errorf("%w: only SELECT statements are allowed, found %s", ErrNotReadOnly, strings.ToUpper(first.text))
+	}
+
+	for i, t := range tokens {
+		if !t.isName() {
+			continue
+		}
+		if t.kind == tokenWord && deniedKeywords[strings.ToUpper(t.text)] {
+			return fmt.Errorf("%w: %s is not allowed", ErrNotReadOnly, strings.ToUpper(t.text))
+		}
+		if deniedFunctions[t.name()] && i+1 < len(tokens) && tokens[i+1].isPunct("(") {
+			return fmt.Errorf("%w: function %s is not allowed", ErrNotReadOnly, t.name())
+		}
+	}
+
+	if len(allowedTables) == 0 {
+		return nil
+	}
+
+	var allowlist [][]string
+	for _, entry := range allowedTables {
+		allowlist = append(allowlist, qualifiedName(tokenize(entry)))
+	}
+
+	for _, table := range referencedTables(tokens) {
+		if !tableAllowed(table, allowlist) {
+			return fmt.Errorf("%w: %s", ErrTableNotAllowed, strings.Join(table, "."))
+		}
+	}
+
+	return nil
+}
+
+// qualifiedName returns the parts of a possibly qualified name such as
+// schema.table or schema.* at the start of tokens.
+func qualifiedName(tokens []sqlToken) []string {
+	var parts []string
+	for i := 0; i < len(tokens); i += 2 {
+		switch {
+		case tokens[i].isName():
+			parts = append(parts, tokens[i].name())
+		case tokens[i].isPunct("*"):
+			parts = append(parts, "*")
+		default:
+			return parts
+		}
+		if i+1 >= len(tokens) || !tokens[i+1].isPunct(".") {
+			break
+		}
+	}
+	return parts
+}
+
+func tableAllowed(table []string, allowlist [][]string) bool {
+	for _, entry := range allowlist {
+		if len(entry) != len(table) {
+			continue
+		}
+		match := true
+		for i := range entry {
+			if entry[i] != table[i] && !(entry[i] == "*" && i == len(entry)-1) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// referencedTables returns the tables in the FROM and JOIN clauses of
+// tokens and of its subqueries and parenthesized joins, leaving out common
+// table expressions and set-returning functions.
+func referencedTables(tokens []sqlToken) [][]string {
+	var tables [][]string
+
+	// levels tracks, for the query and every open parenthesis, whether it
+	// holds a (sub)query or a parenthesized join and whether a FROM clause
+	// is being read in it. FROM inside function calls such as
+	// extract(year FROM ts) does not name tables. ctes maps the common
+	// table expressions declared at a level to the index from which on they
+	// hide tables of the same name; they are visible in the level and the
+	// levels under it.
+	type level struct {
+		query, from, recursive bool
+		ctes                   map[string]int
+	}
+	levels := []level{{query: true}}
+
+	isCTE := func(name string, at int) bool {
+		for k := len(levels) - 1; k >= 0; k-- {
+			if from, ok := levels[k].ctes[name]; ok && at > from {
+				return true
+			}
+		}
+		return false
+	}
+
+	readTable := func(i int) int {
+		for i < len(tokens) && (tokens[i].is("LATERAL") || tokens[i].is("ONLY")) {
+			i++
+		}
+		if i+1 < len(tokens) && tokens[i].is("ROWS") && tokens[i+1].is("FROM") {
+			// ROWS FROM (f(...), ...) lists functions
+			return i + 2
+		}
+		if i >= len(tokens) || !tokens[i].isName() {
+			return i
+		}
+		start := i
+		for i+2 < len(tokens) && tokens[i+1].isPunct(".") && tokens[i+2].isName() {
+			i += 2
+		}
+		i++
+		if i < len(tokens) && tokens[i].isPunct("(") {
+			// a function call
+			return i
+		}
+		name := qualifiedName(tokens[start:i])
+		if len(name) == 1 && isCTE(name[0], start) {
+			return i
+		}
+		tables = append(tables, name)
+		return i
+	}
+
+	i := 0
+	for i < len(tokens) {
+		t := tokens[i]
+		current := &levels[len(levels)-1]
+		switch {
+		case t.isPunct("("):
+			var prev, next sqlToken
+			if i > 0 {
+				prev = tokens[i-1]
+			}
+			if i+1 < len(tokens) {
+				next = tokens[i+1]
+			}
+			switch {
+			case next.is("SELECT") || next.is("WITH") || next.is("VALUES") || next.is("TABLE"):
+				levels = append(levels, level{query: true})
+				i++
+			case current.query && current.from && (prev.is("FROM") || prev.is("JOIN") || prev.isPunct(",") || prev.isPunct("(")):
+				// a parenthesized join such as FROM (a JOIN b ON ...)
+				levels = append(levels, level{query: true, from: true})
+				i = readTable(i + 1)
+			default:
+				levels = append(levels, level{query: next.isPunct("(")})
+				i++
+			}
+		case t.isPunct(")"):
+			if len(levels) > 1 {
+				levels = levels[:len(levels)-1]
+			}
+			i++
+		case !current.query:
+			i++
+		case t.is("WITH"):
+			current.recursive = i+1 < len(tokens) && tokens[i+1].is("RECURSIVE")
+			i++
+		case t.isName() && i > 0 && (tokens[i-1].is("WITH") || tokens[i-1].is("RECURSIVE") || tokens[i-1].isPunct(",")) && cteBody(tokens, i) > 0:
+			if current.ctes == nil {
+				current.ctes = map[string]int{}
+			}
+			// only a recursive query sees itself, others read the table
+			// of the same name in their body
+			visible := closingParen(tokens, cteBody(tokens, i))
+			if current.recursive {
+				visible = i
+			}
+			current.ctes[t.name()] = visible
+			i++
+		case t.is("TABLE"):
+			// TABLE is reserved, so it starts a TABLE statement, e.g. in
+			// (TABLE t) or UNION TABLE t
+			current.from = false
+			i = readTable(i + 1)
+		case t.is("FROM") || t.is("JOIN"):
+			current.from = true
+			i = readTable(i + 1)
+		case t.isPunct(",") && current.from:
+			i = readTable(i + 1)
+		case t.kind == tokenWord && clauseKeywords[strings.ToUpper(t.text)]:
+			current.from = false
+			i++
+		default:
+			i++
+		}
+	}
+
+	return tables
+}
+
+// cteBody returns the index of the parenthesis opening the body of the
+// common table expression declared by the name at i, name [(columns)] AS
+// [[NOT] MATERIALIZED] (, or 0 if it does not declare one. A column alias
+// such as SELECT a, b AS c is not followed by a parenthesis.
+func cteBody(tokens []sqlToken, i int) int {
+	j := i + 1
+	if j < len(tokens) && tokens[j].isPunct("(") {
+		j = closingParen(tokens, j) + 1
+	}
+	if j >= len(tokens) || !tokens[j].is("AS") {
+		return 0
+	}
+	for j++; j < len(tokens) && (tokens[j].is("NOT") || tokens[j].is("MATERIALIZED")); j++ {
+	}
+	if j < len(tokens) && tokens[j].isPunct("(") {
+		return j
+	}
+	return 0
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// start, or the last index if it is not closed.
+func closingParen(tokens []sqlToken, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isPunct("("):
+			depth++
+		case tokens[i].isPunct(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// ValidTableName reports whether entry is a table, schema.table or
+// schema.* entry of a table allowlist.
+func ValidTableName(entry string) bool {
+	tokens := tokenize(entry)
+	name := qualifiedName(tokens)
+	// every part but the last is followed by a dot
+	if len(name) == 0 || len(tokens) != 2*len(name)-1 {
+		return false
+	}
+	for _, part := range name[:len(name)-1] {
+		if part == "*" {
+			return false
+		}
+	}
+	return len(name) > 1 || name[0] != "*"
+}
@@ -0,0 +1,119 @@
+package utility_test
+
+import (
+	"errors"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	allowlist := []string{"sales.orders", "sales.customers", "reference.*", "lookup"}
+
+	tests := []struct {
+		name      string
+		query     string
+		allowlist []string
+		wantErr   error
+	}{
+		{name: "select", query: "SELECT count(*) FROM sales.orders WHERE created_at >= $start"},
+		{name: "trailing semicolon", query: "SELECT 1;"},
+		{name: "with", query: "WITH o AS (SELECT * FROM sales.orders) SELECT count(*) FROM o"},
+		{name: "parenthesized union", query: "(SELECT 1) UNION ALL (SELECT 2)"},
+		{name: "keywords in literals", query: "SELECT 'DROP TABLE x; DELETE FROM y' AS note, \"update\" FROM t -- INSERT"},
+		{name: "keywords in dollar quotes", query: "SELECT $q$ DELETE FROM t $q$"},
+		{name: "keywords in comments", query: "SELECT 1 /* TRUNCATE t; */"},
+
+		{name: "drop", query: "DROP TABLE sales.orders", wantErr: utility.ErrNotReadOnly},
+		{name: "delete", query: "delete from sales.orders", wantErr: utility.ErrNotReadOnly},
+		{name: "update", query: "UPDATE sales.orders SET status = 'x'", wantErr: utility.ErrNotReadOnly},
+		{name: "insert", query: "INSERT INTO t VALUES (1)", wantErr: utility.ErrNotReadOnly},
+		{name: "copy", query: "COPY t TO '/tmp/t.csv'", wantErr: utility.ErrNotReadOnly},
+		{name: "multiple statements", query: "SELECT 1; SELECT 2", wantErr: utility.ErrNotReadOnly},
+		{name: "statement after comment", query: "SELECT 1; -- x\nDROP TABLE t", wantErr: utility.ErrNotReadOnly},
+		{name: "data-modifying with", query: "WITH d AS (DELETE FROM t RETURNING *) SELECT count(*) FROM d", wantErr: utility.ErrNotReadOnly},
+		{name: "select into", query: "SELECT * INTO backup FROM t", wantErr: utility.ErrNotReadOnly},
+		{name: "row locks", query: "SELECT * FROM t FOR UPDATE", wantErr: utility.ErrNotReadOnly},
+		{name: "side effect function", query: "SELECT nextval('seq')", wantErr: utility.ErrNotReadOnly},
+		{name: "quoted side effect function", query: `SELECT "setval"('s', 1)`, wantErr: utility.ErrNotReadOnly},
+		{name: "qualified quoted side effect function", query: `SELECT pg_catalog."set_config"('x', 'y', false)`, wantErr: utility.ErrNotReadOnly},
+		{name: "quoted names are not folded", query: `SELECT "SetVal"(1)`},
+		{name: "notify", query: "SELECT pg_notify('channel', 'x')", wantErr: utility.ErrNotReadOnly},
+		{name: "try advisory lock", query: "SELECT pg_try_advisory_lock(1)", wantErr: utility.ErrNotReadOnly},
+		{name: "large object", query: "SELECT lo_from_bytea(0, 'x')", wantErr: utility.ErrNotReadOnly},
+		{name: "switch wal", query: "SELECT pg_switch_wal()", wantErr: utility.ErrNotReadOnly},
+		{name: "dblink send query", query: "SELECT dblink_send_query('c', 'DELETE FROM t')", wantErr: utility.ErrNotReadOnly},
+		{name: "sleep", query: "SELECT pg_sleep(3600)", wantErr: utility.ErrNotReadOnly},
+		{name: "empty", query: " -- nothing\n", wantErr: utility.ErrNotReadOnly},
+
+		{name: "allowed tables", query: "SELECT * FROM sales.orders o JOIN sales.customers c ON c.id = o.customer_id", allowlist: allowlist},
+		{name: "schema wildcard", query: "SELECT * FROM reference.countries, lookup", allowlist: allowlist},
+		{name: "case folding", query: `SELECT * FROM SALES.Orders, "sales"."customers"`, allowlist: allowlist},
+		{name: "cte names", query: "WITH recent AS (SELECT * FROM sales.orders), x (a) AS MATERIALIZED (SELECT 1) SELECT * FROM recent, x", allowlist: allowlist},
+		{name: "functions", query: "SELECT extract(year FROM created_at) FROM sales.orders, generate_series(1, 3) g", allowlist: allowlist},
+		{name: "allowed table subquery", query: "SELECT count(*) FROM (TABLE sales.orders) t", allowlist: allowlist},
+		{name: "allowed parenthesized join", query: "SELECT * FROM (sales.orders o JOIN sales.customers c ON c.id = o.customer_id), lookup", allowlist: allowlist},
+		{name: "cte in subquery", query: "SELECT * FROM sales.orders WHERE id IN (WITH recent AS (SELECT id FROM sales.orders) SELECT id FROM recent)", allowlist: allowlist},
+		{name: "recursive cte", query: "WITH RECURSIVE r (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM r WHERE n < 3) SELECT * FROM r", allowlist: allowlist},
+		{name: "chained ctes", query: "WITH a AS (SELECT * FROM sales.orders), b AS (SELECT * FROM a) SELECT * FROM b", allowlist: allowlist},
+		{name: "rows from", query: "SELECT * FROM ROWS FROM (generate_series(1, 3), unnest(ARRAY[1])) AS g (a, b)", allowlist: allowlist},
+		{name: "join using", query: "SELECT * FROM sales.orders JOIN sales.customers USING (customer_id, region) WHERE a IN (1, 2)", allowlist: allowlist},
+
+		{name: "table outside allowlist", query: "SELECT * FROM hr.salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "unqualified table", query: "SELECT * FROM orders", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "quoted case", query: `SELECT * FROM sales."Orders"`, allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "comma join", query: "SELECT * FROM sales.orders o JOIN sales.customers c ON true, hr.salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "subquery", query: "SELECT (SELECT max(amount) FROM hr.salaries) FROM sales.orders", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "array subquery", query: "SELECT ARRAY(SELECT name FROM hr.salaries)", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "exists", query: "SELECT 1 WHERE EXISTS (SELECT 1 FROM hr.salaries)", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "column alias is not a cte", query: "SELECT 1, salaries AS s FROM salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "table statement", query: "TABLE hr.salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "table subquery", query: "SELECT count(*) FROM (TABLE hr.salaries) t", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "table cte", query: "WITH x AS (TABLE hr.salaries) SELECT count(*) FROM x", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "union table", query: "SELECT * FROM sales.orders UNION ALL TABLE hr.salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "parenthesized join", query: "SELECT count(*) FROM (hr.salaries CROSS JOIN sales.orders)", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "joined parenthesized join", query: "SELECT * FROM sales.orders JOIN (hr.salaries s CROSS JOIN sales.orders o) ON true", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "nested parenthesized join", query: "SELECT * FROM ((sales.orders JOIN hr.salaries USING (id)))", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "cte out of scope", query: "SELECT * FROM (WITH salaries AS (SELECT 1) SELECT * FROM salaries) a, salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "cte body reads the table", query: "WITH salaries AS (SELECT * FROM salaries) SELECT * FROM salaries", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+		{name: "lateral", query: "SELECT * FROM sales.orders, LATERAL (SELECT * FROM hr.salaries) s", allowlist: allowlist, wantErr: utility.ErrTableNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utility.CheckReadOnly(tt.query, tt.allowlist)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidTableName(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  bool
+	}{
+		{"orders", true},
+		{"sales.orders", true},
+		{`"Sales"."Orders"`, true},
+		{"sales.*", true},
+		{"warehouse.sales.orders", true},
+		{"", false},
+		{"*", false},
+		{"*.orders", false},
+		{"sales.", false},
+		{"sales orders", false},
+		{"orders; DROP TABLE x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			if got := utility.ValidTableName(tt.entry); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package utility
+
+import (
+	"strings"
+)
+
+// Placeholder is a named $parameter reference found in a query. Start and
+// End are byte offsets into the query, End being exclusive.
+type Placeholder struct {
+	Name  string
+	Start int
+	End   int
+}
+
+// ParsePlaceholders tokenizes query and returns every $name placeholder in
+// the order it appears. String literals, quoted identifiers, dollar-quoted
+// strings and comments are skipped, positional parameters such as $1 are
+// ignored and a placeholder name always extends to the end of the
+// identifier, so $id never matches the start of $id_list.
+func ParsePlaceholders(query string) []Placeholder {
+	var placeholders []Placeholder
+
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == '\'':
+			escapes := i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i < 2 || !isIdentChar(query[i-2]))
+			i = skipQuoted(query, i, '\'', escapes)
+		case c == '"':
+			i = skipQuoted(query, i, '"', false)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return placeholders
+			}
+			i += end + 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+		case c == '$':
+			if i > 0 && isIdentChar(query[i-1]) {
+				// $ inside an identifier such as foo$bar
+				i++
+				continue
+			}
+			if tag, ok := dollarQuoteTag(query, i); ok {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end < 0 {
+					return placeholders
+				}
+				i += len(tag) + end + len(tag)
+				continue
+			}
+			if i+1 < len(query) && isIdentStart(query[i+1]) {
+				end := i + 2
+				for end < len(query) && isIdentChar(query[end]) && query[end] != '$' {
+					end++
+				}
+				placeholders = append(placeholders, Placeholder{
+					Name:  query[i+1 : end],
+					Start: i,
+					End:   end,
+				})
+				i = end
+				continue
+			}
+			i++
+		default:
+			i++
+		}
+	}
+
+	return placeholders
+}
+
+// skipQuoted returns the offset just past the literal starting at start.
+// A doubled quote character is an escaped quote; backslash escapes are only
+// honoured for E-prefixed strings.
+func skipQuoted(query string, start int, quote byte, backslashEscapes bool) int {
+	i := start + 1
+	for i < len(query) {
+		switch query[i] {
+		case '\\':
+			if backslashEscapes {
+				i += 2
+				continue
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(query)
+}
+
+// skipBlockComment returns the offset just past the (possibly nested)
+// comment starting at start.
+func skipBlockComment(query string, start int) int {
+	depth := 0
+	i := start
+	for i < len(query) {
+		switch {
+		case strings.HasPrefix(query[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(query[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(query)
+}
+
+// dollarQuoteTag reports whether a dollar-quoted string ($$ or $tag$)
+// starts at start and returns its opening tag.
+func dollarQuoteTag(query string, start int) (string, bool) {
+	i := start + 1
+	if i < len(query) && query[i] == '$' {
+		return "$$", true
+	}
+	if i >= len(query) || !isIdentStart(query[i]) {
+		return "", false
+	}
+	for i < len(query) && isIdentChar(query[i]) && query[i] != '$' {
+		i++
+	}
+	if i < len(query) && query[i] == '$' {
+		return query[start : i+1], true
+	}
+	return "", false
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
@@ -0,0 +1,194 @@
+package utility_test
+
+import (
+	"reflect"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+func TestParsePlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "simple",
+			query: "SELECT * FROM t WHERE id = $id AND status = $status",
+			want:  []string{"id", "status"},
+		},
+		{
+			name:  "identifier boundaries",
+			query: "SELECT * FROM t WHERE id = $id OR id = ANY($id_list)",
+			want:  []string{"id", "id_list"},
+		},
+		{
+			name:  "string literals are skipped",
+			query: "SELECT '$id', 'it''s $x', E'\\'$y' FROM t WHERE a = $a",
+			want:  []string{"a"},
+		},
+		{
+			name:  "quoted identifiers are skipped",
+			query: `SELECT "$col" FROM t WHERE b = $b`,
+			want:  []string{"b"},
+		},
+		{
+			name:  "comments are skipped",
+			query: "SELECT 1 -- $ignored\nFROM t /* $also /* nested $x */ $y */ WHERE c = $c",
+			want:  []string{"c"},
+		},
+		{
+			name:  "dollar quoted strings are skipped",
+			query: "SELECT $$ $a $$, $fn$ $b $fn$ WHERE d = $d",
+			want:  []string{"d"},
+		},
+		{
+			name:  "positional parameters and casts are ignored",
+			query: "SELECT $1::int, created_at::date FROM t WHERE e = $e::text",
+			want:  []string{"e"},
+		},
+		{
+			name:  "dollar inside identifier",
+			query: "SELECT foo$bar FROM t WHERE f = $f",
+			want:  []string{"f"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range utility.ParsePlaceholders(tt.query) {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePlaceholders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		parameters string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "prefix names are not clobbered",
+			query:      "SELECT * FROM t WHERE id = $id AND id IN ($id_list)",
+			parameters: `{"id": 1, "id_list": "a"}`,
+			want:       "SELECT * FROM t WHERE id = 1 AND id IN ('a')",
+		},
+		{
+			name:       "quotes and backslashes are escaped",
+			query:      "SELECT * FROM t WHERE name = $name AND path = $path",
+			parameters: `{"name": "O'Brien", "path": "C:\\tmp'"}`,
+			want:       `SELECT * FROM t WHERE name = 'O''Brien' AND path = E'C:\\tmp'''`,
+		},
+		{
+			name:       "typed values",
+			query:      "SELECT $f, $b, $n",
+			parameters: `{"f": 1.5, "b": true, "n": null}`,
+			want:       "SELECT 1.5, true, NULL",
+		},
+		{
+			name:       "placeholders inside literals are left alone",
+			query:      "SELECT '$id' WHERE id = $id",
+			parameters: `{"id": 7}`,
+			want:       "SELECT '$id' WHERE id = 7",
+		},
+		{
+			name:       "unknown placeholders are left alone",
+			query:      "SELECT $missing",
+			parameters: `{}`,
+			want:       "SELECT $missing",
+		},
+		{
+			name:       "unsupported type",
+			query:      "SELECT $a",
+			parameters: `{"a": [1, 2]}`,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := utility.FormatQuery(tt.query, []byte(tt.parameters))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FormatQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FormatQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindQuery(t *testing.T) {
+	query := "SELECT * FROM t WHERE id = $id AND name = $name OR parent_id = $id AND x = '$id'"
+	parameters := `{"id": 3, "name": "a", "unused": false}`
+
+	got, args, err := utility.BindQuery(query, []byte(parameters))
+	if err != nil {
+		t.Fatalf("BindQuery() error = %v", err)
+	}
+
+	want := "SELECT * FROM t WHERE id = $1 AND name = $2 OR parent_id = $1 AND x = '$id'"
+	if got != want {
+		t.Errorf("BindQuery() query = %q, want %q", got, want)
+	}
+
+	wantArgs := []interface{}{int64(3), "a"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("BindQuery() args = %#v, want %#v", args, wantArgs)
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	query := "SELECT * FROM t WHERE id = $id AND name = $name OR parent_id = $id AND day = $day AND note = '$skipped'"
+	parameters := `{"id": 3, "name": "a", "limit": 10, "verbose": true}`
+
+	tests := []struct {
+		name     string
+		bind     bool
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "formatted",
+			wantSQL: "SELECT * FROM t WHERE id = 3 AND name = 'a' OR parent_id = 3 AND day = $day AND note = '$skipped'",
+		},
+		{
+			name:     "bound",
+			bind:     true,
+			wantSQL:  "SELECT * FROM t WHERE id = $1 AND name = $2 OR parent_id = $1 AND day = $day AND note = '$skipped'",
+			wantArgs: []interface{}{int64(3), "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := utility.RenderQuery(query, []byte(parameters), tt.bind)
+			if err != nil {
+				t.Fatalf("RenderQuery() error = %v", err)
+			}
+			if got.SQL != tt.wantSQL {
+				t.Errorf("RenderQuery() SQL = %q, want %q", got.SQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("RenderQuery() args = %#v, want %#v", got.Args, tt.wantArgs)
+			}
+			if want := []string{"id", "name"}; !reflect.DeepEqual(got.Substituted, want) {
+				t.Errorf("RenderQuery() substituted = %v, want %v", got.Substituted, want)
+			}
+			if want := []string{"day"}; !reflect.DeepEqual(got.Unresolved, want) {
+				t.Errorf("RenderQuery() unresolved = %v, want %v", got.Unresolved, want)
+			}
+			if want := []string{"limit", "verbose"}; !reflect.DeepEqual(got.Unused, want) {
+				t.Errorf("RenderQuery() unused = %v, want %v", got.Unused, want)
+			}
+		})
+	}
+}
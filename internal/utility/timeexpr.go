@@ -0,0 +1,266 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats of time parameters. Without a format the time is passed
+// on as a time.Time and rendered with timestampFormat.
+const (
+	TimeFormatTimestamp = "timestamp"
+	TimeFormatDate      = "date"
+	TimeFormatEpoch     = "epoch"
+	TimeFormatISO8601   = "iso8601"
+)
+
+// TimeFormats lists the supported output formats of time parameters.
+var TimeFormats = []string{TimeFormatTimestamp, TimeFormatDate, TimeFormatEpoch, TimeFormatISO8601}
+
+// timeFunctions are the functions time expressions start with, mapped to
+// the unit they truncate the current time to.
+var timeFunctions = map[string]string{
+	"now":            "",
+	"today":          "d",
+	"start_of_week":  "w",
+	"start_of_month": "mo",
+	"start_of_year":  "y",
+}
+
+var (
+	timeFunctionRX = regexp.MustCompile(`^(now|today|start_of_week|start_of_month|start_of_year)\('?([A-Za-z0-9_/+\-]*)'?\)`)
+	timeOffsetRX   = regexp.MustCompile(`^(\d+(?:\.\d+)?)(ns|us|µs|ms|mo|s|m|h|d|w|y)`)
+	timeUnitRX     = regexp.MustCompile(`^(mo|s|m|h|d|w|y)`)
+)
+
+// IsTimeExpression reports whether s is meant as a time expression, i.e.
+// starts with a call of one of the time functions.
+func IsTimeExpression(s string) bool {
+	s = strings.TrimSpace(s)
+	for name := range timeFunctions {
+		if strings.HasPrefix(s, name+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTimeExpression evaluates a time expression at now. An expression is
+// a function followed by any number of operations, applied left to right:
+//
+//	now() today() start_of_week() start_of_month() start_of_year()
+//	+<offset> -<offset>  offsets combine units, e.g. 1d12h or 1y6mo
+//	/<unit>              truncates to the start of the unit
+//
+// Units are ns, us, ms, s, m (minutes), h, d, w, mo (months) and y. Weeks
+// start on Monday. Months and years are calendar months and years; days
+// that do not exist in the target month are clamped to its last day. Only
+// ns to h may be fractional.
+//
+// Functions take an optional IANA timezone, e.g. today('Europe/Berlin'),
+// which overrides loc. Days, weeks, months and years are counted in that
+// timezone.
+func ParseTimeExpression(expr string, now time.Time, loc *time.Location) (time.Time, error) {
+	s := strings.Join(strings.Fields(expr), "")
+
+	m := timeFunctionRX.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, errors.New("time expressions start with now(), today(), start_of_week(), start_of_month() or start_of_year()")
+	}
+	if m[2] != "" {
+		var err error
+		loc, err = time.LoadLocation(m[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone %q", m[2])
+		}
+	}
+
+	t := now.In(loc)
+	if unit := timeFunctions[m[1]]; unit != "" {
+		t = truncateTime(t, unit)
+	}
+
+	rest := s[len(m[0]):]
+	for rest != "" {
+		op := rest[0]
+		rest = rest[1:]
+
+		switch op {
+		case '+', '-':
+			end := strings.IndexAny(rest, "+-/")
+			if end < 0 {
+				end = len(rest)
+			}
+			var err error
+			t, err = addOffset(t, rest[:end], op == '-')
+			if err != nil {
+				return time.Time{}, err
+			}
+			rest = rest[end:]
+		case '/':
+			unit := timeUnitRX.FindString(rest)
+			if unit == "" || (len(rest) > len(unit) && !strings.ContainsRune("+-/", rune(rest[len(unit)]))) {
+				return time.Time{}, fmt.Errorf("invalid truncation unit %q", rest)
+			}
+			t = truncateTime(t, unit)
+			rest = rest[len(unit):]
+		default:
+			return time.Time{}, fmt.Errorf("unexpected %q, want +, - or /", string(op)+rest)
+		}
+	}
+
+	return t, nil
+}
+
+// addOffset adds or, with negative, subtracts an offset such as 1d12h.
+func addOffset(t time.Time, offset string, negative bool) (time.Time, error) {
+	if offset == "" {
+		return time.Time{}, errors.New("missing offset after + or -")
+	}
+
+	var (
+		years, months, days int
+		duration            time.Duration
+	)
+	for rest := offset; rest != ""; {
+		m := timeOffsetRX.FindStringSubmatch(rest)
+		if m == nil {
+			return time.Time{}, fmt.Errorf("invalid offset %q", offset)
+		}
+		rest = rest[len(m[0]):]
+
+		number, unit := m[1], m[2]
+		switch unit {
+		case "d", "w", "mo", "y":
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid offset %q: %s must be a whole number", offset, unit)
+			}
+			switch unit {
+			case "d":
+				days += n
+			case "w":
+				days += 7 * n
+			case "mo":
+				months += n
+			case "y":
+				years += n
+			}
+		default:
+			d, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid offset %q: %v", offset, err)
+			}
+			duration += d
+		}
+	}
+
+	if negative {
+		years, months, days, duration = -years, -months, -days, -duration
+	}
+	t = addMonths(t, 12*years+months)
+	return t.AddDate(0, 0, days).Add(duration), nil
+}
+
+// addMonths adds calendar months, clamping the day to the end of the
+// target month rather than overflowing into the next one.
+func addMonths(t time.Time, months int) time.Time {
+	if months == 0 {
+		return t
+	}
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func truncateTime(t time.Time, unit string) time.Time {
+	year, month, day := t.Date()
+	loc := t.Location()
+
+	switch unit {
+	case "s":
+		return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, loc)
+	case "m":
+		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, loc)
+	case "h":
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, loc)
+	case "d":
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case "w":
+		sinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-sinceMonday, 0, 0, 0, 0, loc)
+	case "mo":
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	case "y":
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	}
+	return t
+}
+
+// formatTime returns the value a time parameter is passed on as in format.
+func formatTime(t time.Time, format string) (interface{}, error) {
+	switch format {
+	case "":
+		return t, nil
+	case TimeFormatTimestamp:
+		return t.Format(timestampFormat), nil
+	case TimeFormatDate:
+		return t.Format("2006-01-02"), nil
+	case TimeFormatEpoch:
+		return t.Unix(), nil
+	case TimeFormatISO8601:
+		return t.Format(time.RFC3339), nil
+	}
+	return nil, fmt.Errorf("unknown time format %q, want one of %s", format, strings.Join(TimeFormats, ", "))
+}
+
+// resolveTimeParameter evaluates a time parameter given as an object:
+//
+//	{"value": "today()-1d", "format": "date", "timezone": "Europe/Berlin"}
+//
+// format and timezone are optional; a timezone in the expression itself
+// takes precedence.
+func resolveTimeParameter(parameter map[string]interface{}, now time.Time) (interface{}, error) {
+	var value, format, timezone string
+	for key, v := range parameter {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", key)
+		}
+		switch key {
+		case "value":
+			value = s
+		case "format":
+			format = s
+		case "timezone":
+			timezone = s
+		default:
+			return nil, fmt.Errorf("unknown field %q, want value, format or timezone", key)
+		}
+	}
+	if value == "" {
+		return nil, errors.New("value is required")
+	}
+
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", timezone)
+		}
+	}
+
+	t, err := ParseTimeExpression(value, now, loc)
+	if err != nil {
+		return nil, err
+	}
+	return formatTime(t, format)
+}
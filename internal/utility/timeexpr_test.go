@@ -0,0 +1,139 @@
+package utility_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+func TestParseTimeExpression(t *testing.T) {
+	// a Sunday, at the end of a 31-day month
+	now := time.Date(2024, time.March, 31, 15, 4, 5, 500, time.UTC)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{expr: "now()", want: now},
+		{expr: "now()-1d", want: time.Date(2024, time.March, 30, 15, 4, 5, 500, time.UTC)},
+		{expr: "now()-1.5h", want: time.Date(2024, time.March, 31, 13, 34, 5, 500, time.UTC)},
+		{expr: "now() - 1d 12h", want: time.Date(2024, time.March, 30, 3, 4, 5, 500, time.UTC)},
+		{expr: "now()-1mo", want: time.Date(2024, time.February, 29, 15, 4, 5, 500, time.UTC)},
+		{expr: "now()+1y", want: time.Date(2025, time.March, 31, 15, 4, 5, 500, time.UTC)},
+		{expr: "now()-1y6mo", want: time.Date(2022, time.September, 30, 15, 4, 5, 500, time.UTC)},
+		{expr: "now()-1mo-1mo", want: time.Date(2024, time.January, 29, 15, 4, 5, 500, time.UTC)},
+		{expr: "now()/h", want: time.Date(2024, time.March, 31, 15, 0, 0, 0, time.UTC)},
+		{expr: "now()-1d/d", want: time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC)},
+		{expr: "now()/mo+1mo-1s", want: time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC)},
+		{expr: "today()", want: time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{expr: "today()-7d", want: time.Date(2024, time.March, 24, 0, 0, 0, 0, time.UTC)},
+		{expr: "start_of_week()", want: time.Date(2024, time.March, 25, 0, 0, 0, 0, time.UTC)},
+		{expr: "start_of_month()-1mo", want: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "start_of_year()", want: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "today('America/New_York')", want: time.Date(2024, time.March, 31, 0, 0, 0, 0, newYork)},
+		{expr: "now(UTC)", want: now},
+		{expr: "now()-1.5d", wantErr: true},
+		{expr: "now()-", wantErr: true},
+		{expr: "now()-1x", wantErr: true},
+		{expr: "now()/q", wantErr: true},
+		{expr: "now()/month", wantErr: true},
+		{expr: "now()*2", wantErr: true},
+		{expr: "today('Mars/Olympus')", wantErr: true},
+		{expr: "yesterday()", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := utility.ParseTimeExpression(tt.expr, now, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimeExpression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeParameters(t *testing.T) {
+	now := time.Now().UTC()
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		parameters string
+		want       interface{}
+		wantErr    string
+	}{
+		{
+			name:       "date",
+			parameters: `{"t": {"value": "start_of_month()", "format": "date", "timezone": "UTC"}}`,
+			want:       startOfMonth.Format("2006-01-02"),
+		},
+		{
+			name:       "timestamp",
+			parameters: `{"t": {"value": "start_of_month('UTC')", "format": "timestamp"}}`,
+			want:       startOfMonth.Format("2006-01-02 15:04:05"),
+		},
+		{
+			name:       "epoch",
+			parameters: `{"t": {"value": "start_of_month()", "format": "epoch", "timezone": "UTC"}}`,
+			want:       startOfMonth.Unix(),
+		},
+		{
+			name:       "iso8601",
+			parameters: `{"t": {"value": "start_of_month()", "format": "iso8601", "timezone": "UTC"}}`,
+			want:       startOfMonth.Format(time.RFC3339),
+		},
+		{
+			name:       "inline timezone wins",
+			parameters: `{"t": {"value": "start_of_month('UTC')", "format": "iso8601", "timezone": "Asia/Tokyo"}}`,
+			want:       startOfMonth.Format(time.RFC3339),
+		},
+		{
+			name:       "unknown format",
+			parameters: `{"t": {"value": "now()", "format": "rfc822"}}`,
+			wantErr:    `invalid time parameter t: unknown time format "rfc822"`,
+		},
+		{
+			name:       "unknown field",
+			parameters: `{"t": {"value": "now()", "tz": "UTC"}}`,
+			wantErr:    `invalid time parameter t: unknown field "tz"`,
+		},
+		{
+			name:       "missing value",
+			parameters: `{"t": {"format": "date"}}`,
+			wantErr:    "invalid time parameter t: value is required",
+		},
+		{
+			name:       "invalid expression",
+			parameters: `{"t": "now()-1q"}`,
+			wantErr:    "invalid time expression for t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := utility.RenderQuery("SELECT $t", []byte(tt.parameters), true)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RenderQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderQuery() error = %v", err)
+			}
+			if got.Values["t"] != tt.want {
+				t.Errorf("RenderQuery() value = %#v, want %#v", got.Values["t"], tt.want)
+			}
+		})
+	}
+}
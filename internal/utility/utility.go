@@ -3,87 +3,250 @@ package utility
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const timestampFormat = "2006-01-02 15:04:05"
+
+// FormatQuery renders every $name placeholder in query as an SQL literal.
+// It is the fallback for data gateways that cannot bind parameters; prefer
+// BindQuery wherever possible.
 func FormatQuery(query string, parametersJson json.RawMessage) (string, error) {
+	values, err := resolveParameters(parametersJson)
+	if err != nil {
+		return "", err
+	}
+	return formatQuery(query, values), nil
+}
+
+func formatQuery(query string, values map[string]interface{}) string {
+	var sb strings.Builder
+	last := 0
+	for _, p := range ParsePlaceholders(query) {
+		val, ok := values[p.Name]
+		if !ok {
+			continue
+		}
+		sb.WriteString(query[last:p.Start])
+		sb.WriteString(renderLiteral(val))
+		last = p.End
+	}
+	sb.WriteString(query[last:])
+
+	return sb.String()
+}
+
+// BindQuery rewrites every $name placeholder in query into a positional
+// parameter ($1, $2, ...) and returns the typed values to bind alongside it.
+// A name used more than once is bound once. Placeholders without a matching
+// parameter are left untouched.
+func BindQuery(query string, parametersJson json.RawMessage) (string, []interface{}, error) {
+	values, err := resolveParameters(parametersJson)
+	if err != nil {
+		return "", nil, err
+	}
+	queryStr, args := bindQuery(query, values)
+	return queryStr, args, nil
+}
+
+func bindQuery(query string, values map[string]interface{}) (string, []interface{}) {
+	args := []interface{}{}
+	positions := map[string]int{}
+
+	var sb strings.Builder
+	last := 0
+	for _, p := range ParsePlaceholders(query) {
+		val, ok := values[p.Name]
+		if !ok {
+			continue
+		}
+		pos, seen := positions[p.Name]
+		if !seen {
+			args = append(args, val)
+			pos = len(args)
+			positions[p.Name] = pos
+		}
+		sb.WriteString(query[last:p.Start])
+		sb.WriteString("$" + strconv.Itoa(pos))
+		last = p.End
+	}
+	sb.WriteString(query[last:])
+
+	return sb.String(), args
+}
+
+// PrepareQuery binds the parameters when bind is true and otherwise renders
+// them as literals, in which case the returned args are nil.
+func PrepareQuery(query string, parametersJson json.RawMessage, bind bool) (string, []interface{}, error) {
+	if bind {
+		return BindQuery(query, parametersJson)
+	}
+	queryStr, err := FormatQuery(query, parametersJson)
+	return queryStr, nil, err
+}
+
+// Rendering is what PrepareQuery makes of a query, for inspecting
+// parameter substitution without running the query.
+type Rendering struct {
+	SQL  string        `json:"sql"`
+	Args []interface{} `json:"args,omitempty"`
+	// Values are the parameters after resolving time expressions.
+	Values map[string]interface{} `json:"values"`
+	// Substituted and Unresolved list the placeholders with and without a
+	// parameter, Unused the parameters without a placeholder.
+	Substituted []string `json:"substituted"`
+	Unresolved  []string `json:"unresolved"`
+	Unused      []string `json:"unused"`
+}
+
+// RenderQuery prepares query like PrepareQuery and reports which
+// placeholders were substituted. Every list is sorted and free of
+// duplicates.
+func RenderQuery(query string, parametersJson json.RawMessage, bind bool) (*Rendering, error) {
+	values, err := resolveParameters(parametersJson)
+	if err != nil {
+		return nil, err
+	}
+
+	rendering := &Rendering{
+		Values:      values,
+		Substituted: []string{},
+		Unresolved:  []string{},
+		Unused:      []string{},
+	}
+	if bind {
+		rendering.SQL, rendering.Args = bindQuery(query, values)
+	} else {
+		rendering.SQL = formatQuery(query, values)
+	}
+
+	used := map[string]bool{}
+	for _, p := range ParsePlaceholders(query) {
+		if used[p.Name] {
+			continue
+		}
+		used[p.Name] = true
+		if _, ok := values[p.Name]; ok {
+			rendering.Substituted = append(rendering.Substituted, p.Name)
+		} else {
+			rendering.Unresolved = append(rendering.Unresolved, p.Name)
+		}
+	}
+	for name := range values {
+		if !used[name] {
+			rendering.Unused = append(rendering.Unused, name)
+		}
+	}
+	sort.Strings(rendering.Substituted)
+	sort.Strings(rendering.Unresolved)
+	sort.Strings(rendering.Unused)
+
+	return rendering, nil
+}
+
+// resolveParameters converts the JSON parameters into typed values:
+// strings, int64, float64, bool, time.Time for time expressions and nil.
+// Time parameters given as objects are formatted as they ask for, see
+// resolveTimeParameter. All time expressions are evaluated at the same
+// instant.
+func resolveParameters(parametersJson json.RawMessage) (map[string]interface{}, error) {
 	var parameters map[string]interface{}
 	err := json.Unmarshal(parametersJson, &parameters)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal parameters: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
 	}
 
+	now := time.Now()
+	values := make(map[string]interface{}, len(parameters))
 	for key, val := range parameters {
-		placeholder := fmt.Sprintf("$%s", key)
-		var replacement string
 		switch v := val.(type) {
 		case string:
-			if strings.HasPrefix(v, "now()") { // timestamp-like string
-				rep, err := parseTimestamp(v)
+			if IsTimeExpression(v) {
+				ts, err := ParseTimeExpression(v, now, time.Local)
 				if err != nil {
-					return "", fmt.Errorf("invalid timestamp format for %v: %v", key, val)
+					return nil, fmt.Errorf("invalid time expression for %v: %v", key, err)
 				}
-				replacement = rep
+				values[key] = ts
 			} else { // regular string
-				replacement = fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''"))
+				values[key] = v
 			}
+		case map[string]interface{}:
+			ts, err := resolveTimeParameter(v, now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time parameter %v: %v", key, err)
+			}
+			values[key] = ts
 		case float64: // JSON numbers are unmarshaled as float64 by default
 			if v == float64(int64(v)) { // Check if it's actually an integer value
-				replacement = fmt.Sprintf("%d", int64(v))
+				values[key] = int64(v)
 			} else {
-				replacement = fmt.Sprintf("%f", v)
+				values[key] = v
 			}
-		case bool:
-			replacement = strconv.FormatBool(v)
+		case bool, nil:
+			values[key] = v
 		default:
-			return "", fmt.Errorf("unsupported parameter type for %v: %v", key, val)
+			return nil, fmt.Errorf("unsupported parameter type for %v: %v", key, val)
 		}
-		query = strings.ReplaceAll(query, placeholder, replacement)
 	}
 
-	return query, nil
+	return values, nil
 }
 
-func parseTimestamp(input string) (string, error) {
-	now := time.Now()
+func renderLiteral(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteLiteral(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return quoteLiteral(v.Format(timestampFormat))
+	default:
+		return quoteLiteral(fmt.Sprint(v))
+	}
+}
 
-	if input == "now()" {
-		return fmt.Sprintf("'%s'", now.Format("2006-01-02 15:04:05")), nil
+// quoteLiteral quotes s as an SQL string literal. Strings containing
+// backslashes are written as E-prefixed literals so they are escaped the same way
+// regardless of standard_conforming_strings.
+func quoteLiteral(s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if strings.Contains(s, `\`) {
+		return `E'` + strings.ReplaceAll(s, `\`, `\\`) + `'`
 	}
+	return "'" + s + "'"
+}
 
-	if strings.HasPrefix(input, "now()-") {
-		durationStr := strings.TrimPrefix(input, "now()-")
-		timestamp := time.Now()
+// MergeParameters overlays the top-level keys of overrides on top of
+// defaults. Either argument may be empty.
+func MergeParameters(defaults json.RawMessage, overrides json.RawMessage) (json.RawMessage, error) {
+	merged := map[string]interface{}{}
 
-		if strings.HasSuffix(durationStr, "d") {
-			days, err := strconv.Atoi(strings.TrimSuffix(durationStr, "d"))
-			if err != nil {
-				return "", fmt.Errorf("invalid day duration: %v", err)
-			}
-			timestamp = timestamp.AddDate(0, 0, -days)
-		} else if strings.HasSuffix(durationStr, "w") {
-			weeks, err := strconv.Atoi(strings.TrimSuffix(durationStr, "w"))
-			if err != nil {
-				return "", fmt.Errorf("invalid week duration: %v", err)
-			}
-			timestamp = timestamp.AddDate(0, 0, -7*weeks)
-		} else if strings.HasSuffix(durationStr, "y") {
-			years, err := strconv.Atoi(strings.TrimSuffix(durationStr, "y"))
-			if err != nil {
-				return "", fmt.Errorf("invalid year duration: %v", err)
-			}
-			timestamp = timestamp.AddDate(0, 0, -365*years)
-		} else {
-			duration, err := time.ParseDuration(strings.ReplaceAll(durationStr, " ", ""))
-			if err != nil {
-				return "", fmt.Errorf("invalid duration format: %v", err)
-			}
-			timestamp = timestamp.Add(-duration)
+	if len(defaults) > 0 {
+		err := json.Unmarshal(defaults, &merged)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal default parameters: %w", err)
 		}
+	}
 
-		return fmt.Sprintf("'%s'", timestamp.Format("2006-01-02 15:04:05")), nil
+	if len(overrides) > 0 {
+		var values map[string]interface{}
+		err := json.Unmarshal(overrides, &values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
+		}
+		for key, val := range values {
+			merged[key] = val
+		}
 	}
-	return "", fmt.Errorf("unsupported timestamp format")
+
+	return json.Marshal(merged)
 }
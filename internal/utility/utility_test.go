@@ -0,0 +1,65 @@
+package utility_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"xcaliber/data-quality-metrics-framework/internal/utility"
+)
+
+func TestMergeParameters(t *testing.T) {
+	tests := []struct {
+		name      string
+		defaults  string
+		overrides string
+		want      map[string]interface{}
+		wantErr   bool
+	}{
+		{
+			name:     "defaults only",
+			defaults: `{"limit": 10, "status": "active"}`,
+			want:     map[string]interface{}{"limit": 10.0, "status": "active"},
+		},
+		{
+			name:      "overrides replace defaults",
+			defaults:  `{"limit": 10, "status": "active"}`,
+			overrides: `{"limit": 5, "since": "now()-1d"}`,
+			want:      map[string]interface{}{"limit": 5.0, "status": "active", "since": "now()-1d"},
+		},
+		{
+			name:      "no defaults",
+			overrides: `{"limit": 5}`,
+			want:      map[string]interface{}{"limit": 5.0},
+		},
+		{
+			name: "nothing supplied",
+			want: map[string]interface{}{},
+		},
+		{
+			name:      "overrides not an object",
+			defaults:  `{"limit": 10}`,
+			overrides: `[1, 2]`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := utility.MergeParameters(json.RawMessage(tt.defaults), json.RawMessage(tt.overrides))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(merged, &got); err != nil {
+				t.Fatalf("could not unmarshal merged parameters: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
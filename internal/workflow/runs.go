@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
+
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// RunStatusRunning is the status of runs that have not finished. Runs are
+// in the Temporal workflow execution status, e.g. COMPLETED, FAILED or
+// CANCELED, once they have.
+const RunStatusRunning = "RUNNING"
+
+// Run is an asynchronous execution of RunQueryWorkflow.
+type Run struct {
+	RunID         uuid.UUID      `json:"run_id"`
+	QueryID       uuid.NullUUID  `json:"query_id"`
+	DataProductID uuid.UUID      `json:"data_product_id"`
+	Name          string         `json:"name"`
+	Status        string         `json:"status"`
+	StartedAt     time.Time      `json:"started_at"`
+	ClosedAt      *time.Time     `json:"closed_at,omitempty"`
+	Result        *runner.Result `json:"result,omitempty"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// Runs starts RunQueryWorkflow on demand, so that callers do not have to
+// wait for a query in an HTTP request.
+type Runs struct {
+	Client    client.Client
+	TaskQueue string
+}
+
+// RunWorkflowID returns the ID of the workflow execution of a run.
+func RunWorkflowID(runID uuid.UUID) string {
+	return "run-" + runID.String()
+}
+
+// Start starts RunQueryWorkflow for query. Queries that only carry a
+// QueryID run the stored query with query.Parameters over its defaults.
+func (rs *Runs) Start(ctx context.Context, query database.Query) (*Run, error) {
+	input, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &Run{
+		RunID:         uuid.New(),
+		QueryID:       uuid.NullUUID{UUID: query.QueryID, Valid: query.QueryID != uuid.Nil},
+		DataProductID: query.DataProductID,
+		Name:          query.Name,
+		Status:        RunStatusRunning,
+	}
+
+	// the memo identifies the run without decoding the workflow input
+	options := client.StartWorkflowOptions{
+		ID:        RunWorkflowID(run.RunID),
+		TaskQueue: rs.TaskQueue,
+		Memo: map[string]interface{}{
+			"query_id":        query.QueryID.String(),
+			"data_product_id": query.DataProductID.String(),
+			"name":            query.Name,
+		},
+	}
+
+	_, err = rs.Client.ExecuteWorkflow(ctx, options, RunQueryWorkflowName, json.RawMessage(input))
+	if err != nil {
+		return nil, err
+	}
+
+	run.StartedAt = time.Now()
+	return run, nil
+}
+
+// Get returns the run with its result once it has finished.
+func (rs *Runs) Get(ctx context.Context, runID uuid.UUID) (*Run, bool, error) {
+	desc, err := rs.Client.DescribeWorkflowExecution(ctx, RunWorkflowID(runID), "")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	info := desc.GetWorkflowExecutionInfo()
+
+	run := &Run{
+		RunID:     runID,
+		Status:    strings.TrimPrefix(enumspb.WorkflowExecutionStatus_name[int32(info.GetStatus())], "WORKFLOW_EXECUTION_STATUS_"),
+		StartedAt: info.GetStartTime().AsTime(),
+	}
+	if info.GetCloseTime() != nil {
+		closedAt := info.GetCloseTime().AsTime()
+		run.ClosedAt = &closedAt
+	}
+
+	err = decodeMemo(info.GetMemo(), map[string]interface{}{
+		"query_id":        &run.QueryID.UUID,
+		"data_product_id": &run.DataProductID,
+		"name":            &run.Name,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	run.QueryID.Valid = run.QueryID.UUID != uuid.Nil
+
+	switch info.GetStatus() {
+	case enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+		err = rs.Client.GetWorkflow(ctx, RunWorkflowID(runID), "").Get(ctx, &run.Result)
+		if err != nil {
+			return nil, false, err
+		}
+	case enumspb.WORKFLOW_EXECUTION_STATUS_FAILED, enumspb.WORKFLOW_EXECUTION_STATUS_TIMED_OUT:
+		err = rs.Client.GetWorkflow(ctx, RunWorkflowID(runID), "").Get(ctx, nil)
+		run.Error = failureMessage(err)
+	}
+
+	return run, true, nil
+}
+
+// Cancel requests cancellation of a running run, which cancels the query.
+func (rs *Runs) Cancel(ctx context.Context, runID uuid.UUID) error {
+	return rs.Client.CancelWorkflow(ctx, RunWorkflowID(runID), "")
+}
+
+func decodeMemo(memo *commonpb.Memo, fields map[string]interface{}) error {
+	dc := converter.GetDefaultDataConverter()
+	for key, v := range fields {
+		payload, ok := memo.GetFields()[key]
+		if !ok {
+			continue
+		}
+		var s string
+		err := dc.FromPayload(payload, &s)
+		if err != nil {
+			return err
+		}
+		switch v := v.(type) {
+		case *uuid.UUID:
+			*v, _ = uuid.Parse(s)
+		case *string:
+			*v = s
+		}
+	}
+	return nil
+}
+
+// failureMessage returns the error a run failed with, without the workflow
+// and activity errors Temporal wraps it in.
+func failureMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		return appErr.Error()
+	}
+	var timeoutErr *temporal.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,191 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"xcaliber/data-quality-metrics-framework/internal/database"
+
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// RunQueryWorkflowName is the workflow type RunQueryWorkflow is registered
+// under on the worker.
+const RunQueryWorkflowName = "RunQueryWorkflow"
+
+type QuerySchedule struct {
+	ScheduleID     string          `json:"schedule_id"`
+	QueryID        uuid.UUID       `json:"query_id"`
+	Cron           string          `json:"cron"`
+	Parameters     json.RawMessage `json:"parameters,omitempty"`
+	Paused         bool            `json:"paused"`
+	Note           string          `json:"note,omitempty"`
+	NextRunTimes   []time.Time     `json:"next_run_times"`
+	RecentRunTimes []time.Time     `json:"recent_run_times"`
+}
+
+// Scheduler manages the Temporal Schedules that run stored queries
+// periodically. There is at most one schedule per query.
+type Scheduler struct {
+	Client    client.Client
+	TaskQueue string
+}
+
+func ScheduleID(queryID uuid.UUID) string {
+	return "query-" + queryID.String()
+}
+
+// CreateSchedule schedules RunQueryWorkflow for the stored query with the
+// given cron expression, updating the existing schedule for that query if
+// there is one.
+func (s *Scheduler) CreateSchedule(
+	ctx context.Context,
+	queryID uuid.UUID,
+	cron string,
+	parameters json.RawMessage,
+	paused bool,
+) (*QuerySchedule, error) {
+	input, err := json.Marshal(database.Query{QueryID: queryID, Parameters: parameters})
+	if err != nil {
+		return nil, err
+	}
+
+	// the cron expression is kept in the action memo because Temporal
+	// rewrites CronExpressions into calendar specs
+	memo := map[string]interface{}{"cron": cron}
+	if parameters != nil {
+		memo["parameters"] = parameters
+	}
+
+	spec := client.ScheduleSpec{
+		CronExpressions: []string{cron},
+	}
+	action := &client.ScheduleWorkflowAction{
+		ID:        ScheduleID(queryID),
+		Workflow:  RunQueryWorkflowName,
+		Args:      []interface{}{json.RawMessage(input)},
+		TaskQueue: s.TaskQueue,
+		Memo:      memo,
+	}
+
+	_, err = s.Client.ScheduleClient().Create(ctx, client.ScheduleOptions{
+		ID:     ScheduleID(queryID),
+		Spec:   spec,
+		Action: action,
+		Paused: paused,
+	})
+	if errors.Is(err, temporal.ErrScheduleAlreadyRunning) {
+		handle := s.Client.ScheduleClient().GetHandle(ctx, ScheduleID(queryID))
+		err = handle.Update(ctx, client.ScheduleUpdateOptions{
+			DoUpdate: func(current client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+				schedule := current.Description.Schedule
+				schedule.Spec = &spec
+				schedule.Action = action
+				if schedule.State == nil {
+					schedule.State = &client.ScheduleState{}
+				}
+				schedule.State.Paused = paused
+				return &client.ScheduleUpdate{Schedule: &schedule}, nil
+			},
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, _, err := s.GetSchedule(ctx, queryID)
+	return schedule, err
+}
+
+func (s *Scheduler) GetSchedule(ctx context.Context, queryID uuid.UUID) (*QuerySchedule, bool, error) {
+	handle := s.Client.ScheduleClient().GetHandle(ctx, ScheduleID(queryID))
+
+	desc, err := handle.Describe(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	schedule := &QuerySchedule{
+		ScheduleID:     handle.GetID(),
+		QueryID:        queryID,
+		Paused:         desc.Schedule.State.Paused,
+		Note:           desc.Schedule.State.Note,
+		NextRunTimes:   desc.Info.NextActionTimes,
+		RecentRunTimes: []time.Time{},
+	}
+	for _, action := range desc.Info.RecentActions {
+		schedule.RecentRunTimes = append(schedule.RecentRunTimes, action.ActualTime)
+	}
+
+	if action, ok := desc.Schedule.Action.(*client.ScheduleWorkflowAction); ok {
+		dc := converter.GetDefaultDataConverter()
+		if payload, ok := action.Memo["cron"].(*commonpb.Payload); ok {
+			err = dc.FromPayload(payload, &schedule.Cron)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		if payload, ok := action.Memo["parameters"].(*commonpb.Payload); ok {
+			err = dc.FromPayload(payload, &schedule.Parameters)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	return schedule, true, nil
+}
+
+func (s *Scheduler) DeleteSchedule(ctx context.Context, queryID uuid.UUID) (bool, error) {
+	err := s.Client.ScheduleClient().GetHandle(ctx, ScheduleID(queryID)).Delete(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Scheduler) PauseSchedule(ctx context.Context, queryID uuid.UUID, note string) (bool, error) {
+	err := s.Client.ScheduleClient().GetHandle(ctx, ScheduleID(queryID)).Pause(ctx, client.SchedulePauseOptions{Note: note})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Scheduler) ResumeSchedule(ctx context.Context, queryID uuid.UUID, note string) (bool, error) {
+	err := s.Client.ScheduleClient().GetHandle(ctx, ScheduleID(queryID)).Unpause(ctx, client.ScheduleUnpauseOptions{Note: note})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func isNotFound(err error) bool {
+	var notFound *serviceerror.NotFound
+	return errors.As(err, &notFound)
+}
+
+// IsInvalidSchedule reports whether Temporal rejected a schedule definition,
+// for example because of a malformed cron expression.
+func IsInvalidSchedule(err error) bool {
+	var invalid *serviceerror.InvalidArgument
+	return errors.As(err, &invalid)
+}
@@ -3,77 +3,168 @@ package workflow
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"time"
-	datagateway "xcaliber/data-quality-metrics-framework/internal/data_gateway"
 	"xcaliber/data-quality-metrics-framework/internal/database"
 	"xcaliber/data-quality-metrics-framework/internal/metrics"
+	"xcaliber/data-quality-metrics-framework/internal/runner"
 	"xcaliber/data-quality-metrics-framework/internal/utility"
 
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
+const (
+	// heartbeatTimeout is how long Temporal waits for a heartbeat before it
+	// considers the worker running the activity lost and cancels it.
+	heartbeatTimeout  = 30 * time.Second
+	heartbeatInterval = 10 * time.Second
+
+	// MaxAttempts is how often a failing query is run before the workflow
+	// fails.
+	MaxAttempts = 5
+)
+
 type TemporalWorkflow struct {
-	DataGatewayURL string
-	Logger         *slog.Logger
+	Runner *runner.Runner
+	DB     *database.DB
+	Logger *slog.Logger
 }
 
-func (twf *TemporalWorkflow) RunQueryWorkflow(ctx workflow.Context, queryJson json.RawMessage) error {
+// RunQueryWorkflow runs the query in queryJson and returns its result
+// without the result rows, which could exceed the size limit of Temporal
+// payloads.
+func (twf *TemporalWorkflow) RunQueryWorkflow(ctx workflow.Context, queryJson json.RawMessage) (*runner.Result, error) {
 	// activity options
 	ao := workflow.ActivityOptions{
 		StartToCloseTimeout: time.Hour * 1,
+		HeartbeatTimeout:    heartbeatTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    5 * time.Minute,
+			MaximumAttempts:    MaxAttempts,
+		},
 	}
 	ctx = workflow.WithActivityOptions(ctx, ao)
 
 	// execute activity
-	err := workflow.ExecuteActivity(ctx, twf.RunQueryActivity, queryJson).Get(ctx, nil)
+	var result *runner.Result
+	err := workflow.ExecuteActivity(ctx, twf.RunQueryActivity, queryJson).Get(ctx, &result)
 	if err != nil {
 		twf.Logger.Error("Error while executing activity: ", slog.Any("err", err))
-		return err
+		return nil, err
 	}
 
-	return nil
+	return result, nil
 }
 
-func (twf *TemporalWorkflow) RunQueryActivity(ctx context.Context, queryJson json.RawMessage) error {
+func (twf *TemporalWorkflow) RunQueryActivity(ctx context.Context, queryJson json.RawMessage) (*runner.Result, error) {
+	stop := heartbeat(ctx)
+	defer stop()
+
 	query := database.Query{}
 	err := json.Unmarshal(queryJson, &query)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	formattedQuery, err := utility.FormatQuery(query.Query, query.Parameters)
-	if err != nil {
-		return err
+	// inputs that only carry a query_id refer to a query in the catalog
+	if query.QueryID != uuid.Nil && query.Query == "" {
+		stored, found, err := twf.DB.GetQuery(ctx, query.QueryID)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, temporal.NewNonRetryableApplicationError(fmt.Sprintf("query %s not found", query.QueryID), "QueryNotFound", nil)
+		}
+		stored.Parameters, err = utility.MergeParameters(stored.DefaultParameters, query.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		query = *stored
+	}
+
+	result, err := twf.Runner.Run(ctx, query)
+	if result != nil {
+		// see RunQueryWorkflow
+		result.Rows = nil
 	}
-	results, err := datagateway.RunQuery(twf.DataGatewayURL, formattedQuery)
 	if err != nil {
 		twf.Logger.Error("Error while running query: ", slog.Any("name", query.Name), slog.Any("err", err))
-		return err
+		if runner.Permanent(err) {
+			// the gateway or the SQL guard rejected the query, running it
+			// again cannot help
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "QueryRejected", err)
+		}
+		return nil, err
 	}
-	if len(results) > 1 || len(results[0]) > 1 {
-		twf.Logger.Error("query does not return a single value, returns multiple rows or columns: %v", slog.Any("name", query.Name))
-		return err
+	if len(query.ValueColumns) > 0 {
+		values := make([]metrics.LabeledValue, 0, len(result.Series))
+		for _, series := range result.Series {
+			values = append(values, metrics.LabeledValue{Column: series.Column, Labels: series.Labels, Value: series.Value})
+		}
+		metrics.SetLabeledValues(query.Name, values, query.DataProductID.String())
+		if result.Status != "" {
+			metrics.SetStatusValue(query.Name, result.Status.Code(), query.DataProductID.String())
+		}
+		twf.Logger.Info("query ran successfully", slog.Any("name", query.Name), slog.Any("series", len(result.Series)), slog.Any("status", result.Status))
+		return result, nil
 	}
-	res := 0.0
-	for _, v := range results[0] {
-		switch y := v.(type) {
-		case int, int8, int16, int32, int64:
-			res = float64(y.(int64))
-		case uint, uint8, uint16, uint32, uint64:
-			res = float64(y.(uint64))
-		case float32:
-			res = float64(y)
-		case float64:
-			res = y
-		default:
-			twf.Logger.Error("query returns non-numeric type", slog.Any("name", query.Name), slog.Any("value", v))
+
+	if result.NoValue {
+		metrics.ClearMetricValue(query.Name, query.DataProductID.String())
+		if result.Status != "" {
+			metrics.SetStatusValue(query.Name, result.Status.Code(), query.DataProductID.String())
 		}
+		twf.Logger.Warn("query returned no value", slog.Any("name", query.Name), slog.Any("status", result.Status))
+		return result, nil
+	}
 
+	if result.Value == nil {
+		twf.Logger.Error("query does not return a single numeric value", slog.Any("name", query.Name), slog.Any("err", result.ValueError))
+		return nil, temporal.NewNonRetryableApplicationError(result.ValueError, "NotSingleValue", nil)
+	}
+
+	metrics.SetMetricValue(query.Name, *result.Value, query.DataProductID.String())
+	if result.Status != "" {
+		metrics.SetStatusValue(query.Name, result.Status.Code(), query.DataProductID.String())
+	}
+	if a := result.Anomaly; a != nil {
+		metrics.SetExpectedBand(query.Name, a.Lower, a.Expected, a.Upper, a.Anomalous, query.DataProductID.String())
+		if a.Anomalous {
+			twf.Logger.Warn("query result is anomalous", slog.Any("name", query.Name), slog.Any("value", *result.Value), slog.Any("anomaly", a))
+		}
 	}
-	metrics.SetMetricValue(query.Name, res, query.DataProductID.String())
-	twf.Logger.Info("query ran successfully: %v, %v", slog.Any("name", query.Name), slog.Any("value", res))
+	twf.Logger.Info("query ran successfully: %v, %v", slog.Any("name", query.Name), slog.Any("value", *result.Value), slog.Any("status", result.Status))
+
+	return result, nil
+}
 
-	return nil
+// heartbeat records activity heartbeats until stop is called, so that
+// long-running queries are not mistaken for a lost worker and a cancelled
+// activity cancels ctx, and with it the query.
+func heartbeat(ctx context.Context) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx)
+			}
+		}
+	}()
 
+	return func() { close(done) }
 }